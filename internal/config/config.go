@@ -2,7 +2,11 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"regexp"
 	"time"
 
 	"gopkg.in/yaml.v2"
@@ -11,16 +15,262 @@ import (
 type Config struct {
 	Host          string        `yaml:"host"`
 	Port          string        `yaml:"port"`
-	Backends      []string      `yaml:"backends"`
+	Backends      []Backend     `yaml:"backends"`
 	Rate_limiting Rate_limiting `yaml:"rate_limiting"`
 	Storage       Storage       `yaml:"storage"`
 	HealthChecker HealthChecker `yaml:"healthcheck"`
 	Balancer      Balancer      `yaml:"balancer"`
+	Transport     Transport     `yaml:"transport"`
+	Retry         Retry         `yaml:"retry"`
+
+	CircuitBreaker CircuitBreaker `yaml:"circuit_breaker"`
+
+	Pools  map[string]Pool `yaml:"pools"`
+	Routes []Route         `yaml:"routes"`
+
+	TrustedProxies []string `yaml:"trusted_proxies"` // Сети доверенных прокси (CIDR или IP)
+
+	TLS         TLS         `yaml:"tls"`
+	UpstreamTLS UpstreamTLS `yaml:"upstream_tls"`
+
+	Streaming Streaming `yaml:"streaming"`
+	HTTP2     HTTP2     `yaml:"http2"`
+
+	UpstreamProtocol string `yaml:"upstream_protocol"` // Протокол к backend-ам пула default
+
+	Reload Reload `yaml:"reload"`
+	Admin  Admin  `yaml:"admin"`
+}
+
+// Admin описывает отдельный слушатель admin API.
+// Без enabled admin API недоступен.
+type Admin struct {
+	Enabled bool              `yaml:"enabled"`
+	Address string            `yaml:"address"` // host:port, по умолчанию 127.0.0.1:9090
+	Socket  string            `yaml:"socket"`  // Путь к unix-сокету вместо address
+	Tokens  map[string]string `yaml:"tokens"`  // Bearer-токены по именам вызывающих
+	TLS     AdminTLS          `yaml:"tls"`
+}
+
+// AdminTLS описывает TLS и mTLS на слушателе admin API.
+type AdminTLS struct {
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file"` // CA клиентских сертификатов, включает mTLS
+}
+
+// Reload описывает перезагрузку конфигурации без перезапуска.
+// По сигналу SIGHUP конфигурация перечитывается всегда.
+type Reload struct {
+	Watch bool `yaml:"watch"` // Перечитывать конфигурацию при изменении файла
+}
+
+// Протоколы соединений с backend-ами.
+const (
+	ProtocolAuto  = "auto"  // HTTP/2 по ALPN для https, HTTP/1.1 для http
+	ProtocolHTTP1 = "http1" // Только HTTP/1.1
+	ProtocolH2C   = "h2c"   // HTTP/2 без TLS (prior knowledge), например для gRPC
+)
+
+// HTTP2 описывает HTTP/2 на входящем слушателе.
+// Поверх TLS HTTP/2 включен всегда.
+type HTTP2 struct {
+	H2C                  bool   `yaml:"h2c"`                    // Принимать HTTP/2 без TLS
+	MaxConcurrentStreams uint32 `yaml:"max_concurrent_streams"` // Потоков на соединение, 0 — по умолчанию
+}
+
+// Streaming описывает проксирование WebSocket и потоковых ответов (SSE, chunked).
+type Streaming struct {
+	FlushInterval time.Duration `yaml:"flush_interval"` // Период сброса ответа клиенту, -1 — после каждой записи
+	IdleTimeout   time.Duration `yaml:"idle_timeout"`   // Время без данных до закрытия потока, 0 — без ограничения
+	MaxDuration   time.Duration `yaml:"max_duration"`   // Максимальная длительность потока, 0 — без ограничения
+	DrainTimeout  time.Duration `yaml:"drain_timeout"`  // Ожидание завершения потоков при остановке, 0 — закрыть сразу
+}
+
+// TLS описывает терминацию TLS на балансировщике.
+type TLS struct {
+	Enabled        bool          `yaml:"enabled"`
+	Certificates   []Certificate `yaml:"certificates"`    // Сертификаты, выбираются по SNI; первый используется по умолчанию
+	MinVersion     string        `yaml:"min_version"`     // Минимальная версия TLS: 1.0, 1.1, 1.2 (по умолчанию) или 1.3
+	CipherSuites   []string      `yaml:"cipher_suites"`   // Разрешенные наборы шифров для TLS 1.0–1.2
+	ReloadInterval time.Duration `yaml:"reload_interval"` // Период проверки файлов сертификатов на изменения
+	RedirectPort   string        `yaml:"redirect_port"`   // Порт HTTP-слушателя с перенаправлением на HTTPS
+}
+
+// Certificate описывает пару файлов сертификата и ключа в формате PEM.
+type Certificate struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// DefaultPool — имя пула, составленного из backend-ов верхнего уровня конфига.
+const DefaultPool = "default"
+
+// Pool описывает именованную группу backend-серверов
+// со своим алгоритмом балансировки и проверкой состояния.
+type Pool struct {
+	Backends      []Backend     `yaml:"backends"`
+	Balancer      Balancer      `yaml:"balancer"`
+	HealthChecker HealthChecker `yaml:"healthcheck"`
+	UpstreamTLS   UpstreamTLS   `yaml:"upstream_tls"`
+	Protocol      string        `yaml:"upstream_protocol"` // auto (по умолчанию), http1 или h2c
+}
+
+// UpstreamTLS описывает TLS-соединения с backend-ами пула
+// для проксируемых запросов и проверок состояния.
+type UpstreamTLS struct {
+	CAFile             string `yaml:"ca_file"`              // Бандл CA для проверки сертификатов backend-ов
+	CertFile           string `yaml:"cert_file"`            // Клиентский сертификат для mTLS
+	KeyFile            string `yaml:"key_file"`             // Ключ клиентского сертификата
+	ServerName         string `yaml:"server_name"`          // Имя сервера для SNI и проверки сертификата
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"` // Отключить проверку сертификатов backend-ов
+}
+
+// Route направляет подходящие запросы в пул backend-ов.
+// Все заданные условия должны выполняться одновременно.
+type Route struct {
+	Host       string            `yaml:"host"`        // Хост запроса, допускается маска "*.example.com"
+	PathPrefix string            `yaml:"path_prefix"` // Префикс пути
+	PathRegex  string            `yaml:"path_regex"`  // Регулярное выражение для пути
+	Methods    []string          `yaml:"methods"`     // Допустимые методы
+	Headers    map[string]string `yaml:"headers"`     // Заголовки и их точные значения
+	Pool       string            `yaml:"pool"`        // Имя пула
+	Rewrite    Rewrite           `yaml:"rewrite"`
+}
+
+// Rewrite описывает переписывание пути и заголовков для маршрута.
+// В значениях заголовков поддерживаются шаблоны {client_ip}, {request_id},
+// {backend}, {pool} и {host}.
+type Rewrite struct {
+	StripPrefix     string      `yaml:"strip_prefix"`     // Префикс пути, который удаляется
+	ReplacePrefix   string      `yaml:"replace_prefix"`   // Префикс, подставляемый вместо strip_prefix
+	PathRegex       string      `yaml:"path_regex"`       // Регулярное выражение для пути
+	PathReplacement string      `yaml:"path_replacement"` // Замена для path_regex, поддерживает $1
+	RequestHeaders  HeaderRules `yaml:"request_headers"`
+	ResponseHeaders HeaderRules `yaml:"response_headers"`
+}
+
+// HeaderRules описывает изменения заголовков: удаление, установку и добавление.
+type HeaderRules struct {
+	Add    map[string]string `yaml:"add"`
+	Set    map[string]string `yaml:"set"`
+	Remove []string          `yaml:"remove"`
+}
+
+// AllPools возвращает все пулы конфига, включая пул DefaultPool
+// из backend-ов верхнего уровня, если они заданы.
+func (c *Config) AllPools() map[string]Pool {
+	pools := make(map[string]Pool, len(c.Pools)+1)
+	for name, pool := range c.Pools {
+		pools[name] = pool
+	}
+	if len(c.Backends) > 0 {
+		pools[DefaultPool] = Pool{
+			Backends:      c.Backends,
+			Balancer:      c.Balancer,
+			HealthChecker: c.HealthChecker,
+			UpstreamTLS:   c.UpstreamTLS,
+			Protocol:      c.UpstreamProtocol,
+		}
+	}
+	return pools
+}
+
+// CircuitBreaker описывает настройки circuit breaker-а backend-серверов.
+type CircuitBreaker struct {
+	Enabled          bool          `yaml:"enabled"`
+	Window           time.Duration `yaml:"window"`             // Окно подсчета доли ошибок
+	MinRequests      int           `yaml:"min_requests"`       // Минимум запросов в окне для открытия
+	ErrorRate        float64       `yaml:"error_rate"`         // Доля ошибок, при которой breaker открывается
+	LatencyThreshold time.Duration `yaml:"latency_threshold"`  // Ответ дольше считается ошибкой, 0 — не учитывать
+	OpenTimeout      time.Duration `yaml:"open_timeout"`       // Время в open до перехода в half-open
+	HalfOpenRequests int           `yaml:"half_open_requests"` // Пробных запросов в half-open
+}
+
+// Retry описывает повтор неудачных запросов на другом backend-сервере.
+type Retry struct {
+	Attempts      int           `yaml:"attempts"`        // Всего попыток, включая первую; 1 — без повторов
+	PerTryTimeout time.Duration `yaml:"per_try_timeout"` // Таймаут попытки до получения заголовков ответа
+	OnStatus      []int         `yaml:"on_status"`       // Коды ответа, при которых запрос повторяется
+	Methods       []string      `yaml:"methods"`         // Методы, которые разрешено повторять
+	Budget        RetryBudget   `yaml:"budget"`
+}
+
+// RetryBudget ограничивает повторы долей от общего потока запросов,
+// чтобы при массовых сбоях повторы не умножали нагрузку на backend-ы.
+type RetryBudget struct {
+	Ratio        float64 `yaml:"ratio"`          // Доля повторов от числа запросов, например 0.2
+	MinPerSecond int     `yaml:"min_per_second"` // Повторов в секунду, разрешенных независимо от доли
+}
+
+// Transport описывает настройки пула соединений к backend-серверам.
+type Transport struct {
+	MaxIdleConns          int           `yaml:"max_idle_conns"`          // Всего простаивающих соединений
+	MaxIdleConnsPerHost   int           `yaml:"max_idle_conns_per_host"` // Простаивающих соединений на backend
+	IdleConnTimeout       time.Duration `yaml:"idle_conn_timeout"`       // Время жизни простаивающего соединения
+	DialTimeout           time.Duration `yaml:"dial_timeout"`            // Таймаут установки соединения
+	TLSHandshakeTimeout   time.Duration `yaml:"tls_handshake_timeout"`   // Таймаут TLS-рукопожатия
+	ResponseHeaderTimeout time.Duration `yaml:"response_header_timeout"` // Таймаут ожидания заголовков ответа, 0 — без ограничения
+}
+
+// Backend описывает backend-сервер и его вес при балансировке.
+type Backend struct {
+	URL         string `yaml:"url"`
+	Weight      int    `yaml:"weight"`
+	HealthCheck *Probe `yaml:"healthcheck"` // Переопределение общих настроек проверки
+}
+
+// UnmarshalYAML позволяет задавать backend как строкой с URL,
+// так и структурой с полями url и weight.
+func (b *Backend) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var rawURL string
+	if err := unmarshal(&rawURL); err == nil {
+		b.URL = rawURL
+		return nil
+	}
+
+	type plain Backend
+	return unmarshal((*plain)(b))
 }
 
 type HealthChecker struct {
-	Interval time.Duration `yaml:"interval"`
-	Timeout  time.Duration `yaml:"timeout"`
+	Interval    time.Duration `yaml:"interval"`
+	Timeout     time.Duration `yaml:"timeout"`
+	Jitter      time.Duration `yaml:"jitter"`      // Максимальная случайная задержка перед проверкой сервера
+	Concurrency int           `yaml:"concurrency"` // Максимальное число одновременных проверок
+	Probe       `yaml:",inline"`
+
+	HealthyThreshold   int     `yaml:"healthy_threshold"`   // Успешных проверок подряд для возврата сервера
+	UnhealthyThreshold int     `yaml:"unhealthy_threshold"` // Неуспешных проверок подряд для исключения сервера
+	Flap               Flap    `yaml:"flap"`
+	Passive            Passive `yaml:"passive"`
+}
+
+// Flap описывает подавление частой смены состояния сервера.
+type Flap struct {
+	Enabled        bool          `yaml:"enabled"`
+	MaxTransitions int           `yaml:"max_transitions"` // Смен состояния в окне до удержания сервера
+	Window         time.Duration `yaml:"window"`          // Окно подсчета смен состояния
+	Hold           time.Duration `yaml:"hold"`            // Время удержания сервера вне балансировки
+}
+
+// Probe описывает HTTP-запрос проверки состояния и ожидаемый ответ.
+type Probe struct {
+	Type           string            `yaml:"type"`            // Тип проверки: http (по умолчанию), tcp или grpc
+	GRPCService    string            `yaml:"grpc_service"`    // Имя сервиса для проверки grpc
+	Path           string            `yaml:"path"`            // Путь проверки, по умолчанию /healthcheck
+	Method         string            `yaml:"method"`          // HTTP-метод, по умолчанию GET
+	Headers        map[string]string `yaml:"headers"`         // Дополнительные заголовки запроса
+	ExpectedStatus []int             `yaml:"expected_status"` // Допустимые коды ответа, по умолчанию 200
+	BodyMatch      string            `yaml:"body_match"`      // Регулярное выражение для тела ответа
+}
+
+// Passive описывает настройки пассивной проверки по проксируемому трафику.
+type Passive struct {
+	Enabled     bool          `yaml:"enabled"`
+	MaxFailures int           `yaml:"max_failures"` // Ошибок подряд до исключения сервера
+	Window      time.Duration `yaml:"window"`       // Окно, в котором считаются ошибки
+	Cooldown    time.Duration `yaml:"cooldown"`     // Время до возврата сервера в балансировку
 }
 
 type Rate_limiting struct {
@@ -39,32 +289,60 @@ type Redis struct {
 
 type Balancer struct {
 	Algorithm string `yaml:"algorithm"`
+	Hash      Hash   `yaml:"hash"`
+	Sticky    Sticky `yaml:"sticky"`
+}
+
+// Sticky описывает настройки привязки клиента к серверу через cookie.
+type Sticky struct {
+	Enabled bool          `yaml:"enabled"`
+	Cookie  string        `yaml:"cookie"` // Имя cookie
+	Secret  string        `yaml:"secret"` // Ключ HMAC-подписи cookie
+	TTL     time.Duration `yaml:"ttl"`    // Время жизни cookie, 0 — сессионная cookie
+}
+
+// Hash описывает настройки алгоритма consistent_hash.
+type Hash struct {
+	Key      string `yaml:"key"`      // Источник ключа: ip, header или cookie
+	Name     string `yaml:"name"`     // Имя заголовка или cookie
+	Replicas int    `yaml:"replicas"` // Количество виртуальных узлов на единицу веса
 }
 
 // MustLoad загружает конфигурацию из файла YAML.
 // Паникует при возникновении ошибок загрузки или парсинга.
 func MustLoad() *Config {
+	config, err := Load(Path())
+	if err != nil {
+		panic(err)
+	}
+	return config
+}
+
+// Path возвращает путь к файлу конфигурации из CONFIG_PATH или путь по умолчанию.
+func Path() string {
 	configPath := os.Getenv("CONFIG_PATH")
 	if configPath == "" {
 		configPath = "../config/config.yaml"
 	}
-	file, err := os.Open(configPath)
+	return configPath
+}
+
+// Load загружает конфигурацию из файла YAML и проверяет ее.
+func Load(path string) (*Config, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	defer file.Close()
 	decoder := yaml.NewDecoder(file)
 	config := &Config{}
-	err = decoder.Decode(config)
-	if err != nil {
-		panic(err)
+	if err := decoder.Decode(config); err != nil {
+		return nil, err
 	}
-	err = validateConfig(config)
-	if err != nil {
-		panic(err)
+	if err := validateConfig(config); err != nil {
+		return nil, err
 	}
-
-	return config
+	return config, nil
 }
 func validateConfig(config *Config) error {
 	if config.Rate_limiting.Rate_per_second <= 0 {
@@ -79,11 +357,22 @@ func validateConfig(config *Config) error {
 	if config.Storage.Redis.Port == 0 {
 		return errors.New("redis port must be set")
 	}
-	if config.HealthChecker.Interval == 0 {
-		return errors.New("healthcheck interval must be set")
+	if err := validateHealthChecker(&config.HealthChecker); err != nil {
+		return err
 	}
-	if config.HealthChecker.Timeout == 0 {
-		return errors.New("healthcheck timeout must be set")
+	if err := validateTransport(&config.Transport); err != nil {
+		return err
+	}
+	if err := validateRetry(&config.Retry); err != nil {
+		return err
+	}
+	if err := validateStreaming(&config.Streaming); err != nil {
+		return err
+	}
+	if config.CircuitBreaker.Enabled {
+		if err := validateCircuitBreaker(&config.CircuitBreaker); err != nil {
+			return err
+		}
 	}
 	if config.Host == "" {
 		return errors.New("host must be set")
@@ -91,13 +380,521 @@ func validateConfig(config *Config) error {
 	if config.Port == "" {
 		return errors.New("port must be set")
 	}
-	if len(config.Backends) == 0 {
+	if config.TLS.Enabled {
+		if err := validateTLS(&config.TLS); err != nil {
+			return err
+		}
+	}
+	if config.Admin.Enabled {
+		if err := validateAdmin(&config.Admin); err != nil {
+			return err
+		}
+	}
+	for _, cidr := range config.TrustedProxies {
+		if net.ParseIP(cidr) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("trusted proxy %q is invalid: %v", cidr, err)
+		}
+	}
+	if len(config.Backends) == 0 && len(config.Pools) == 0 {
+		return errors.New("backends must be set")
+	}
+	if len(config.Backends) > 0 {
+		if err := validateBalancer(&config.Balancer); err != nil {
+			return err
+		}
+		if err := validateBackends(config.Backends); err != nil {
+			return err
+		}
+		if err := validateUpstreamTLS(&config.UpstreamTLS); err != nil {
+			return err
+		}
+		if err := validateProtocol(&config.UpstreamProtocol, config.Backends); err != nil {
+			return err
+		}
+		if _, ok := config.Pools[DefaultPool]; ok {
+			return fmt.Errorf("pool %q is reserved for top-level backends", DefaultPool)
+		}
+	}
+	for name, pool := range config.Pools {
+		if err := validatePool(config, &pool); err != nil {
+			return fmt.Errorf("pool %s: %v", name, err)
+		}
+		config.Pools[name] = pool
+	}
+	return validateRoutes(config)
+}
+
+// validatePool проверяет пул backend-ов. Незаданные поля настроек
+// балансировщика и проверки состояния наследуются из верхнего уровня конфига.
+func validatePool(config *Config, pool *Pool) error {
+	if len(pool.Backends) == 0 {
 		return errors.New("backends must be set")
 	}
-	for _, backend := range config.Backends {
-		if backend == "" {
+	inheritBalancer(&pool.Balancer, config.Balancer)
+	inheritHealthChecker(&pool.HealthChecker, config.HealthChecker)
+	if pool.UpstreamTLS == (UpstreamTLS{}) {
+		pool.UpstreamTLS = config.UpstreamTLS
+	}
+	if err := validateUpstreamTLS(&pool.UpstreamTLS); err != nil {
+		return err
+	}
+	if pool.Protocol == "" {
+		pool.Protocol = config.UpstreamProtocol
+	}
+	if err := validateProtocol(&pool.Protocol, pool.Backends); err != nil {
+		return err
+	}
+	if err := validateBalancer(&pool.Balancer); err != nil {
+		return err
+	}
+	if err := validateHealthChecker(&pool.HealthChecker); err != nil {
+		return err
+	}
+	return validateBackends(pool.Backends)
+}
+
+// inheritBalancer заполняет незаданные поля балансировщика пула
+// значениями из parent.
+func inheritBalancer(b *Balancer, parent Balancer) {
+	if b.Algorithm == "" {
+		b.Algorithm = parent.Algorithm
+	}
+	if b.Hash.Key == "" {
+		b.Hash.Key, b.Hash.Name = parent.Hash.Key, parent.Hash.Name
+	}
+	if b.Hash.Replicas == 0 {
+		b.Hash.Replicas = parent.Hash.Replicas
+	}
+	if b.Sticky == (Sticky{}) {
+		b.Sticky = parent.Sticky
+		return
+	}
+	if b.Sticky.Cookie == "" {
+		b.Sticky.Cookie = parent.Sticky.Cookie
+	}
+	if b.Sticky.Secret == "" {
+		b.Sticky.Secret = parent.Sticky.Secret
+	}
+	if b.Sticky.TTL == 0 {
+		b.Sticky.TTL = parent.Sticky.TTL
+	}
+}
+
+// inheritHealthChecker заполняет незаданные поля проверки состояния пула
+// значениями из parent. Блоки flap и passive наследуются целиком.
+func inheritHealthChecker(hc *HealthChecker, parent HealthChecker) {
+	if hc.Interval == 0 {
+		hc.Interval = parent.Interval
+	}
+	if hc.Timeout == 0 {
+		hc.Timeout = parent.Timeout
+	}
+	if hc.Jitter == 0 {
+		hc.Jitter = parent.Jitter
+	}
+	if hc.Concurrency == 0 {
+		hc.Concurrency = parent.Concurrency
+	}
+	if hc.HealthyThreshold == 0 {
+		hc.HealthyThreshold = parent.HealthyThreshold
+	}
+	if hc.UnhealthyThreshold == 0 {
+		hc.UnhealthyThreshold = parent.UnhealthyThreshold
+	}
+	if hc.Flap == (Flap{}) {
+		hc.Flap = parent.Flap
+	}
+	if hc.Passive == (Passive{}) {
+		hc.Passive = parent.Passive
+	}
+	inheritProbe(&hc.Probe, parent.Probe)
+}
+
+// inheritProbe заполняет незаданные поля проверки значениями из parent.
+func inheritProbe(probe *Probe, parent Probe) {
+	if probe.Type == "" {
+		probe.Type = parent.Type
+	}
+	if probe.GRPCService == "" {
+		probe.GRPCService = parent.GRPCService
+	}
+	if probe.Path == "" {
+		probe.Path = parent.Path
+	}
+	if probe.Method == "" {
+		probe.Method = parent.Method
+	}
+	if probe.Headers == nil {
+		probe.Headers = parent.Headers
+	}
+	if probe.ExpectedStatus == nil {
+		probe.ExpectedStatus = parent.ExpectedStatus
+	}
+	if probe.BodyMatch == "" {
+		probe.BodyMatch = parent.BodyMatch
+	}
+}
+
+func validateBackends(backends []Backend) error {
+	for i := range backends {
+		backend := &backends[i]
+		if backend.URL == "" {
 			return errors.New("backend must be set")
 		}
+		if backend.Weight < 0 {
+			return errors.New("backend weight must not be negative")
+		}
+		if backend.Weight == 0 {
+			backend.Weight = 1
+		}
+		if backend.HealthCheck != nil {
+			if err := validateProbe(backend.HealthCheck); err != nil {
+				return fmt.Errorf("backend %s: %v", backend.URL, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateBalancer(balancer *Balancer) error {
+	if balancer.Algorithm == "consistent_hash" {
+		if err := validateHash(&balancer.Hash); err != nil {
+			return err
+		}
+	}
+	if balancer.Sticky.Enabled {
+		if balancer.Sticky.Secret == "" {
+			return errors.New("sticky secret must be set")
+		}
+		if balancer.Sticky.Secret == placeholderSecret {
+			return fmt.Errorf("sticky secret must be changed from the %q placeholder", placeholderSecret)
+		}
+		if len(balancer.Sticky.Secret) < minStickySecret {
+			return fmt.Errorf("sticky secret must be at least %d characters", minStickySecret)
+		}
+		if balancer.Sticky.Cookie == "" {
+			balancer.Sticky.Cookie = "lb_affinity"
+		}
+	}
+	return nil
+}
+
+func validateHealthChecker(hc *HealthChecker) error {
+	if hc.Interval == 0 {
+		return errors.New("healthcheck interval must be set")
+	}
+	if hc.Timeout == 0 {
+		return errors.New("healthcheck timeout must be set")
+	}
+	if hc.Timeout+hc.Jitter > hc.Interval {
+		return errors.New("healthcheck timeout plus jitter must not exceed interval")
+	}
+	if hc.Jitter < 0 {
+		return errors.New("healthcheck jitter must not be negative")
+	}
+	if hc.Concurrency < 0 {
+		return errors.New("healthcheck concurrency must not be negative")
+	}
+	if hc.Concurrency == 0 {
+		hc.Concurrency = 10
+	}
+	if err := validateProbe(&hc.Probe); err != nil {
+		return err
+	}
+	if hc.HealthyThreshold < 0 || hc.UnhealthyThreshold < 0 {
+		return errors.New("healthcheck thresholds must not be negative")
+	}
+	if hc.HealthyThreshold == 0 {
+		hc.HealthyThreshold = 1
+	}
+	if hc.UnhealthyThreshold == 0 {
+		hc.UnhealthyThreshold = 1
+	}
+	if hc.Flap.Enabled {
+		if err := validateFlap(&hc.Flap); err != nil {
+			return err
+		}
+	}
+	if hc.Passive.Enabled {
+		if err := validatePassive(&hc.Passive); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateRoutes(config *Config) error {
+	for i, route := range config.Routes {
+		if route.Pool == "" {
+			return fmt.Errorf("route %d: pool must be set", i)
+		}
+		if _, ok := config.AllPools()[route.Pool]; !ok {
+			return fmt.Errorf("route %d: unknown pool %q", i, route.Pool)
+		}
+		if route.PathPrefix != "" && route.PathPrefix[0] != '/' {
+			return fmt.Errorf("route %d: path_prefix must start with /", i)
+		}
+		if route.PathRegex != "" {
+			if _, err := regexp.Compile(route.PathRegex); err != nil {
+				return fmt.Errorf("route %d: path_regex is invalid: %v", i, err)
+			}
+		}
+		if route.Rewrite.PathRegex != "" {
+			if _, err := regexp.Compile(route.Rewrite.PathRegex); err != nil {
+				return fmt.Errorf("route %d: rewrite path_regex is invalid: %v", i, err)
+			}
+		}
+		if route.Rewrite.ReplacePrefix != "" && route.Rewrite.StripPrefix == "" {
+			return fmt.Errorf("route %d: rewrite replace_prefix requires strip_prefix", i)
+		}
+	}
+	return nil
+}
+
+func validateHash(hash *Hash) error {
+	switch hash.Key {
+	case "":
+		hash.Key = "ip"
+	case "ip":
+	case "header", "cookie":
+		if hash.Name == "" {
+			return errors.New("hash name must be set for header and cookie keys")
+		}
+	default:
+		return errors.New("hash key must be one of ip, header, cookie")
+	}
+	if hash.Replicas < 0 {
+		return errors.New("hash replicas must not be negative")
+	}
+	if hash.Replicas == 0 {
+		hash.Replicas = 100
+	}
+	return nil
+}
+
+func validatePassive(passive *Passive) error {
+	if passive.MaxFailures <= 0 {
+		return errors.New("passive max_failures must be greater than 0")
+	}
+	if passive.Window <= 0 {
+		return errors.New("passive window must be greater than 0")
+	}
+	if passive.Cooldown <= 0 {
+		return errors.New("passive cooldown must be greater than 0")
+	}
+	return nil
+}
+
+func validateProbe(probe *Probe) error {
+	switch probe.Type {
+	case "", "http", "tcp", "grpc":
+	default:
+		return errors.New("healthcheck type must be one of http, tcp, grpc")
+	}
+	if probe.Path != "" && probe.Path[0] != '/' {
+		return errors.New("healthcheck path must start with /")
+	}
+	for _, code := range probe.ExpectedStatus {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("healthcheck expected_status %d is not a valid HTTP status", code)
+		}
+	}
+	if probe.BodyMatch != "" {
+		if _, err := regexp.Compile(probe.BodyMatch); err != nil {
+			return fmt.Errorf("healthcheck body_match is invalid: %v", err)
+		}
+	}
+	return nil
+}
+
+func validateFlap(flap *Flap) error {
+	if flap.MaxTransitions <= 1 {
+		return errors.New("flap max_transitions must be greater than 1")
+	}
+	if flap.Window <= 0 {
+		return errors.New("flap window must be greater than 0")
+	}
+	if flap.Hold <= 0 {
+		return errors.New("flap hold must be greater than 0")
+	}
+	return nil
+}
+
+func validateTransport(transport *Transport) error {
+	if transport.MaxIdleConns < 0 || transport.MaxIdleConnsPerHost < 0 {
+		return errors.New("transport idle connection limits must not be negative")
+	}
+	if transport.IdleConnTimeout < 0 || transport.DialTimeout < 0 ||
+		transport.TLSHandshakeTimeout < 0 || transport.ResponseHeaderTimeout < 0 {
+		return errors.New("transport timeouts must not be negative")
+	}
+	if transport.MaxIdleConns == 0 {
+		transport.MaxIdleConns = 1000
+	}
+	if transport.MaxIdleConnsPerHost == 0 {
+		transport.MaxIdleConnsPerHost = 100
+	}
+	if transport.IdleConnTimeout == 0 {
+		transport.IdleConnTimeout = 90 * time.Second
+	}
+	if transport.DialTimeout == 0 {
+		transport.DialTimeout = 5 * time.Second
+	}
+	if transport.TLSHandshakeTimeout == 0 {
+		transport.TLSHandshakeTimeout = 5 * time.Second
+	}
+	return nil
+}
+
+func validateRetry(retry *Retry) error {
+	if retry.Attempts < 0 {
+		return errors.New("retry attempts must not be negative")
+	}
+	if retry.PerTryTimeout < 0 {
+		return errors.New("retry per_try_timeout must not be negative")
+	}
+	for _, code := range retry.OnStatus {
+		if code < 500 || code > 599 {
+			return fmt.Errorf("retry on_status %d must be a 5xx status", code)
+		}
+	}
+	if retry.Attempts == 0 {
+		retry.Attempts = 1
+	}
+	if len(retry.OnStatus) == 0 {
+		retry.OnStatus = []int{502, 503, 504}
+	}
+	if len(retry.Methods) == 0 {
+		retry.Methods = []string{"GET", "HEAD", "OPTIONS"}
+	}
+	if retry.Budget.Ratio < 0 || retry.Budget.Ratio > 1 {
+		return errors.New("retry budget ratio must be in [0, 1]")
+	}
+	if retry.Budget.MinPerSecond < 0 {
+		return errors.New("retry budget min_per_second must not be negative")
+	}
+	if retry.Budget == (RetryBudget{}) {
+		retry.Budget = RetryBudget{Ratio: 0.2, MinPerSecond: 10}
+	}
+	return nil
+}
+
+func validateCircuitBreaker(cb *CircuitBreaker) error {
+	if cb.Window <= 0 {
+		return errors.New("circuit_breaker window must be greater than 0")
+	}
+	if cb.OpenTimeout <= 0 {
+		return errors.New("circuit_breaker open_timeout must be greater than 0")
+	}
+	if cb.ErrorRate <= 0 || cb.ErrorRate > 1 {
+		return errors.New("circuit_breaker error_rate must be in (0, 1]")
+	}
+	if cb.MinRequests < 0 || cb.HalfOpenRequests < 0 || cb.LatencyThreshold < 0 {
+		return errors.New("circuit_breaker limits must not be negative")
+	}
+	if cb.MinRequests == 0 {
+		cb.MinRequests = 20
+	}
+	if cb.HalfOpenRequests == 0 {
+		cb.HalfOpenRequests = 3
+	}
+	return nil
+}
+
+func validateTLS(t *TLS) error {
+	if len(t.Certificates) == 0 {
+		return errors.New("tls certificates must be set")
+	}
+	for _, cert := range t.Certificates {
+		if cert.CertFile == "" || cert.KeyFile == "" {
+			return errors.New("tls cert_file and key_file must be set")
+		}
+	}
+	switch t.MinVersion {
+	case "":
+		t.MinVersion = "1.2"
+	case "1.0", "1.1", "1.2", "1.3":
+	default:
+		return fmt.Errorf("tls min_version %q is not supported", t.MinVersion)
+	}
+	if t.ReloadInterval < 0 {
+		return errors.New("tls reload_interval must not be negative")
+	}
+	if t.ReloadInterval == 0 {
+		t.ReloadInterval = 30 * time.Second
+	}
+	return nil
+}
+
+// placeholderSecret — значение из примеров, которое не принимается
+// в качестве токена admin API и ключа подписи sticky cookie.
+const placeholderSecret = "change-me"
+
+// minStickySecret — минимальная длина ключа подписи sticky cookie.
+const minStickySecret = 16
+
+func validateAdmin(admin *Admin) error {
+	if admin.Address != "" && admin.Socket != "" {
+		return errors.New("admin address and socket are mutually exclusive")
+	}
+	if admin.Address == "" && admin.Socket == "" {
+		admin.Address = "127.0.0.1:9090"
+	}
+	if (admin.TLS.CertFile == "") != (admin.TLS.KeyFile == "") {
+		return errors.New("admin tls cert_file and key_file must be set together")
+	}
+	if admin.TLS.ClientCAFile != "" && admin.TLS.CertFile == "" {
+		return errors.New("admin tls client_ca_file requires cert_file and key_file")
+	}
+	if len(admin.Tokens) == 0 && admin.TLS.ClientCAFile == "" {
+		return errors.New("admin requires tokens or tls client_ca_file")
+	}
+	for name, token := range admin.Tokens {
+		if token == "" {
+			return fmt.Errorf("admin token %q must not be empty", name)
+		}
+		if token == placeholderSecret {
+			return fmt.Errorf("admin token %q must be changed from the %q placeholder", name, placeholderSecret)
+		}
+	}
+	return nil
+}
+
+func validateUpstreamTLS(t *UpstreamTLS) error {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return errors.New("upstream_tls cert_file and key_file must be set together")
+	}
+	return nil
+}
+
+func validateStreaming(streaming *Streaming) error {
+	if streaming.FlushInterval < -1 {
+		return errors.New("streaming flush_interval must be -1 or greater")
+	}
+	if streaming.IdleTimeout < 0 || streaming.MaxDuration < 0 || streaming.DrainTimeout < 0 {
+		return errors.New("streaming timeouts must not be negative")
+	}
+	return nil
+}
+
+// validateProtocol проверяет протокол к backend-ам.
+// h2c работает только без TLS, поэтому https-backend-ы с ним недопустимы.
+func validateProtocol(protocol *string, backends []Backend) error {
+	switch *protocol {
+	case "":
+		*protocol = ProtocolAuto
+	case ProtocolAuto, ProtocolHTTP1:
+	case ProtocolH2C:
+		for _, backend := range backends {
+			if u, err := url.Parse(backend.URL); err == nil && u.Scheme == "https" {
+				return fmt.Errorf("backend %s: upstream_protocol h2c requires http backends", backend.URL)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown upstream_protocol %q", *protocol)
 	}
 	return nil
 }
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func load(t *testing.T, data string) *Config {
+	t.Helper()
+	cfg, err := loadErr(t, data)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return cfg
+}
+
+func loadErr(t *testing.T, data string) (*Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+const base = `
+host: 0.0.0.0
+port: "8080"
+rate_limiting:
+  capacity: 10
+  rate_per_second: 1
+storage:
+  redis:
+    host: localhost
+    port: 6379
+healthcheck:
+  interval: 10s
+  timeout: 2s
+  path: /healthcheck
+  healthy_threshold: 2
+balancer:
+  algorithm: leastconn
+`
+
+func TestPoolPartialOverride(t *testing.T) {
+	cfg := load(t, base+`
+pools:
+  api:
+    backends: ["http://api:80"]
+    healthcheck:
+      path: /ready
+      type: tcp
+    balancer:
+      sticky:
+        enabled: true
+        secret: 0123456789abcdef
+`)
+	pool := cfg.Pools["api"]
+
+	hc := pool.HealthChecker
+	if hc.Type != "tcp" || hc.Path != "/ready" {
+		t.Errorf("probe = %q %q, want tcp /ready", hc.Type, hc.Path)
+	}
+	if hc.Interval != 10*time.Second || hc.Timeout != 2*time.Second || hc.HealthyThreshold != 2 {
+		t.Errorf("healthcheck did not inherit unset fields: %+v", hc)
+	}
+
+	b := pool.Balancer
+	if b.Algorithm != "leastconn" {
+		t.Errorf("algorithm = %q, want leastconn", b.Algorithm)
+	}
+	if !b.Sticky.Enabled || b.Sticky.Secret != "0123456789abcdef" || b.Sticky.Cookie != "lb_affinity" {
+		t.Errorf("sticky = %+v, want enabled with pool secret and default cookie", b.Sticky)
+	}
+}
+
+func TestPoolInheritsBlocks(t *testing.T) {
+	cfg := load(t, base+`
+pools:
+  api:
+    backends: ["http://api:80"]
+`)
+	pool := cfg.Pools["api"]
+	if pool.HealthChecker.Path != "/healthcheck" || pool.HealthChecker.Interval != 10*time.Second {
+		t.Errorf("healthcheck = %+v, want top-level block", pool.HealthChecker)
+	}
+	if pool.Balancer.Algorithm != "leastconn" {
+		t.Errorf("algorithm = %q, want leastconn", pool.Balancer.Algorithm)
+	}
+}
+
+func TestStickySecret(t *testing.T) {
+	pool := func(secret string) string {
+		return base + `
+pools:
+  api:
+    backends: ["http://api:80"]
+    balancer:
+      sticky:
+        enabled: true
+        secret: "` + secret + `"
+`
+	}
+	for _, secret := range []string{"change-me", "short"} {
+		if _, err := loadErr(t, pool(secret)); err == nil {
+			t.Errorf("secret %q accepted", secret)
+		}
+	}
+	load(t, pool("0123456789abcdef"))
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"context"
+	"net/http"
+)
+
+type excludedKey struct{}
+
+// WithExcluded возвращает контекст, в котором указанные серверы
+// не должны выбираться балансировщиком (например, при повторе запроса).
+func WithExcluded(ctx context.Context, servers ...*Server) context.Context {
+	prev, _ := ctx.Value(excludedKey{}).(map[*Server]struct{})
+	excluded := make(map[*Server]struct{}, len(prev)+len(servers))
+	for s := range prev {
+		excluded[s] = struct{}{}
+	}
+	for _, s := range servers {
+		excluded[s] = struct{}{}
+	}
+	return context.WithValue(ctx, excludedKey{}, excluded)
+}
+
+// Available сообщает, можно ли направить запрос на сервер: сервер жив,
+// не выводится из балансировки, его circuit breaker пропускает запросы
+// и он не исключен в контексте запроса.
+func (s *Server) Available(r *http.Request) bool {
+	if !s.IsAlive() || s.IsDraining() {
+		return false
+	}
+	if s.Breaker != nil && !s.Breaker.Permits() {
+		return false
+	}
+	if r == nil {
+		return true
+	}
+	excluded, _ := r.Context().Value(excludedKey{}).(map[*Server]struct{})
+	_, skip := excluded[s]
+	return !skip
+}
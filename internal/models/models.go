@@ -2,26 +2,63 @@ package models
 
 import (
 	"fmt"
+	"math"
 	"net/url"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/config"
 )
 
 type Server struct {
-	URL   *url.URL
-	Alive bool
-	Mu    sync.RWMutex
+	URL      *url.URL
+	Alive    bool
+	Weight   int
+	Probe    *config.Probe // Переопределение настроек проверки состояния
+	Breaker  Breaker       // Circuit breaker сервера, nil — не используется
+	Mu       sync.RWMutex
+	inFlight atomic.Int64 // Количество запросов, обрабатываемых сервером в данный момент
+	draining atomic.Bool  // Сервер не принимает новые запросы, обрабатываемые завершаются
+
+	latencyMu   sync.Mutex // Мьютекс для защиты статистики задержек
+	latencyEWMA float64    // Экспоненциально взвешенное среднее времени ответа, нс
+	latencyAt   time.Time  // Время последнего обновления latencyEWMA
+}
+
+// Breaker ограничивает отправку запросов на сервер с высокой долей ошибок.
+type Breaker interface {
+	// Permits сообщает, может ли балансировщик выбрать сервер.
+	Permits() bool
+	// Allow резервирует отправку запроса; false — запрос отправлять нельзя.
+	Allow() bool
+	// Done учитывает результат запроса, зарезервированного Allow.
+	Done(failed bool, latency time.Duration)
+	// Cancel освобождает резерв без учета результата.
+	Cancel()
+	// State возвращает текущее состояние breaker-а.
+	State() string
 }
 
-// NewServers создает список серверов из переданных URL.
+const (
+	latencyDecay   = 10 * time.Second // Время, за которое вклад старых замеров уменьшается в e раз
+	failureLatency = time.Second      // Минимальное время, учитываемое для неудачного запроса
+)
+
+// NewServers создает список серверов из описаний backend-ов в конфиге.
 // Возвращает ошибку при некорректных URL.
-func NewServers(URLs []string) ([]*Server, error) {
-	servers := make([]*Server, len(URLs))
-	for i, u := range URLs {
-		ur, err := url.Parse(u)
+func NewServers(backends []config.Backend) ([]*Server, error) {
+	servers := make([]*Server, len(backends))
+	for i, b := range backends {
+		ur, err := url.Parse(b.URL)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse URL: %v", err)
 		}
-		servers[i] = &Server{URL: ur, Alive: true}
+		weight := b.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		servers[i] = &Server{URL: ur, Alive: true, Weight: weight, Probe: b.HealthCheck}
 	}
 	return servers, nil
 }
@@ -30,3 +67,74 @@ func (s *Server) SetAlive(status bool) {
 	defer s.Mu.Unlock()
 	s.Alive = status
 }
+
+// IsAlive возвращает текущее состояние сервера.
+func (s *Server) IsAlive() bool {
+	s.Mu.RLock()
+	defer s.Mu.RUnlock()
+	return s.Alive
+}
+
+// SetDraining включает или отключает вывод сервера из балансировки
+// без прерывания обрабатываемых запросов.
+func (s *Server) SetDraining(draining bool) {
+	s.draining.Store(draining)
+}
+
+// IsDraining сообщает, выводится ли сервер из балансировки.
+func (s *Server) IsDraining() bool {
+	return s.draining.Load()
+}
+
+// Acquire отмечает начало обработки запроса сервером.
+// Каждому вызову Acquire должен соответствовать вызов Release.
+func (s *Server) Acquire() {
+	s.inFlight.Add(1)
+}
+
+// Release отмечает завершение обработки запроса сервером.
+func (s *Server) Release() {
+	s.inFlight.Add(-1)
+}
+
+// InFlight возвращает количество запросов, обрабатываемых сервером.
+func (s *Server) InFlight() int64 {
+	return s.inFlight.Load()
+}
+
+// ObserveLatency учитывает время ответа сервера в скользящем среднем.
+// Вес старых замеров убывает экспоненциально со временем.
+func (s *Server) ObserveLatency(d time.Duration) {
+	s.latencyMu.Lock()
+	defer s.latencyMu.Unlock()
+
+	now := time.Now()
+	if s.latencyAt.IsZero() {
+		s.latencyEWMA = float64(d)
+	} else {
+		w := math.Exp(-float64(now.Sub(s.latencyAt)) / float64(latencyDecay))
+		s.latencyEWMA = s.latencyEWMA*w + float64(d)*(1-w)
+	}
+	s.latencyAt = now
+}
+
+// ObserveFailure учитывает неудачный запрос как медленный ответ,
+// чтобы быстро отвечающий ошибками сервер не казался самым дешевым.
+// Учитывается d, но не меньше failureLatency.
+func (s *Server) ObserveFailure(d time.Duration) {
+	s.ObserveLatency(max(d, failureLatency))
+}
+
+// Latency возвращает скользящее среднее времени ответа сервера.
+// Без новых замеров значение убывает к нулю, чтобы сервер, переставший
+// выбираться после медленного ответа, снова получил запросы и был перемерен.
+// Возвращает 0, если замеров еще не было.
+func (s *Server) Latency() time.Duration {
+	s.latencyMu.Lock()
+	defer s.latencyMu.Unlock()
+	if s.latencyAt.IsZero() {
+		return 0
+	}
+	w := math.Exp(-float64(time.Since(s.latencyAt)) / float64(latencyDecay))
+	return time.Duration(s.latencyEWMA * w)
+}
@@ -3,30 +3,59 @@ package ratelimiter
 import (
 	"context"
 	"fmt"
-	"net"
+	"maps"
 	"net/http"
 	"sync"
 	"time"
 
 	"github.com/DblMOKRQ/cloud_test_task/internal/router/errs"
+	"github.com/DblMOKRQ/cloud_test_task/internal/router/forwarded"
 	logger "github.com/DblMOKRQ/cloud_test_task/pkg"
 	"github.com/go-redis/redis_rate/v10"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
+// Override — индивидуальный лимит клиента.
+type Override struct {
+	Rate      int       `json:"rate"`
+	Burst     int       `json:"burst"`
+	ExpiresAt time.Time `json:"expires_at"` // Окончание действия лимита, нулевое значение — бессрочно
+}
+
+// limit возвращает параметры лимита для redis_rate.
+func (o Override) limit() redis_rate.Limit {
+	return redis_rate.Limit{Rate: o.Rate, Period: time.Second, Burst: o.Burst}
+}
+
+// active сообщает, действует ли лимит в момент now.
+func (o Override) active(now time.Time) bool {
+	return o.ExpiresAt.IsZero() || now.Before(o.ExpiresAt)
+}
+
+// Status — текущее состояние лимита клиента.
+type Status struct {
+	Rate       int
+	Burst      int
+	Override   bool          // Действует индивидуальный лимит, иначе — лимит по умолчанию
+	ExpiresAt  time.Time     // Окончание действия индивидуального лимита
+	Remaining  int           // Оставшиеся токены
+	ResetAfter time.Duration // Время до полного восстановления токенов
+}
+
 // RedisRateLimiter реализует ограничитель запросов на базе Redis.
 type RedisRateLimiter struct {
 	rdb          *redis.Client
 	limiter      *redis_rate.Limiter
-	defaultRate  int                         // Глобальный лимит по умолчанию
-	defaultBurst int                         // Глобальный burst по умолчанию
-	userLimits   map[string]redis_rate.Limit // Кэш индивидуальных лимитов
+	defaultRate  int                 // Глобальный лимит по умолчанию
+	defaultBurst int                 // Глобальный burst по умолчанию
+	userLimits   map[string]Override // Кэш индивидуальных лимитов, хранящихся в Redis
 	mu           sync.RWMutex
 	log          *logger.Logger
 }
 
-// InitRedisClient инициализирует Redis-клиент для ограничителя запросов.
+// InitRedisClient инициализирует Redis-клиент для ограничителя запросов
+// и загружает индивидуальные лимиты из Redis.
 // Возвращает готовый к работе экземпляр RedisRateLimiter.
 func InitRedisClient(addr string, password string, log *logger.Logger, defaultRate int, defaultBurst int) *RedisRateLimiter {
 	rdb := redis.NewClient(&redis.Options{
@@ -39,14 +68,19 @@ func InitRedisClient(addr string, password string, log *logger.Logger, defaultRa
 	if _, err := rdb.Ping(context.Background()).Result(); err != nil {
 		log.Fatal("Failed to connect to Redis", zap.Error(err))
 	}
-	return &RedisRateLimiter{
+	rrl := &RedisRateLimiter{
 		rdb:          rdb,
 		limiter:      limiter,
 		defaultRate:  defaultRate,
 		defaultBurst: defaultBurst,
-		userLimits:   make(map[string]redis_rate.Limit),
+		userLimits:   make(map[string]Override),
 		log:          log,
 	}
+	if err := rrl.loadOverrides(context.Background(), rdb); err != nil {
+		log.Fatal("Failed to load rate limit overrides", zap.Error(err))
+	}
+	log.Info("Rate limit overrides loaded", zap.Int("overrides", len(rrl.userLimits)))
+	return rrl
 }
 
 // RateLimitMiddleware возвра middleware для ограничения запросов.
@@ -59,26 +93,15 @@ func (rrl *RedisRateLimiter) Close() {
 }
 
 // RateLimitMiddleware возвра middleware для ограничения запросов.
-// Использует IP-адрес клиента как идентификатор.
+// Использует IP-адрес клиента как идентификатор (с учетом доверенных прокси).
 func (rrl *RedisRateLimiter) RateLimitMiddleware(next http.Handler) http.Handler {
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Идентификатор пользователя
-		identifier, _, _ := net.SplitHostPort(r.RemoteAddr)
-
-		rrl.mu.RLock()
-		userLimit, exists := rrl.userLimits[identifier]
-		rrl.mu.RUnlock()
-		var limit redis_rate.Limit
-		if exists {
-			limit = userLimit
-		} else {
-			limit = redis_rate.Limit{
-				Rate:   rrl.defaultRate,
-				Period: time.Second,
-				Burst:  rrl.defaultBurst,
-			}
-		}
+		identifier := forwarded.ClientIP(r)
+
+		current, _ := rrl.effective(identifier)
+		limit := current.limit()
 		res, err := rrl.limiter.Allow(r.Context(), identifier, limit)
 		if err != nil {
 			_ = rrl.limiter.Reset(r.Context(), identifier)
@@ -102,7 +125,7 @@ func (rrl *RedisRateLimiter) RateLimitMiddleware(next http.Handler) http.Handler
 		if res.Allowed == 0 {
 			rrl.log.Warn("Rate limit exceeded",
 				zap.String("identifier", identifier),
-				zap.Int("limit", rrl.defaultBurst),
+				zap.Int("limit", limit.Burst),
 				zap.String("URL", r.URL.String()),
 			)
 			errs.JSONError(w, errs.ErrorResponse{Error: "Rate limit exceeded"}, http.StatusTooManyRequests)
@@ -113,21 +136,104 @@ func (rrl *RedisRateLimiter) RateLimitMiddleware(next http.Handler) http.Handler
 
 }
 
-// SetUserLimit устанавливает кастомные лимиты для указанного пользователя.
-// Возвращает ошибку при невалидных значениях лимитов.
-func (rrl *RedisRateLimiter) SetUserLimit(userID string, newRate, newBurst int) error {
+// effective возвращает лимит, действующий для пользователя,
+// и true, если это индивидуальный лимит.
+func (rrl *RedisRateLimiter) effective(userID string) (Override, bool) {
+	rrl.mu.RLock()
+	defer rrl.mu.RUnlock()
+	if o, ok := rrl.userLimits[userID]; ok && o.active(time.Now()) {
+		return o, true
+	}
+	return Override{Rate: rrl.defaultRate, Burst: rrl.defaultBurst}, false
+}
+
+// SetUserLimit устанавливает кастомные лимиты для указанного пользователя
+// на всех экземплярах балансировщика.
+// При ttl > 0 лимит действует ограниченное время, затем снова действуют лимиты по умолчанию.
+// Возвращает ошибку при невалидных значениях лимитов или недоступности Redis.
+func (rrl *RedisRateLimiter) SetUserLimit(ctx context.Context, userID string, newRate, newBurst int, ttl time.Duration) error {
 	if newRate <= 0 || newBurst <= 0 {
 		return fmt.Errorf("rate and burst must be positive")
 	}
+	if ttl < 0 {
+		return fmt.Errorf("ttl must not be negative")
+	}
 
-	// Сохраняем новый лимит в кэше
+	override := Override{Rate: newRate, Burst: newBurst}
+	if ttl > 0 {
+		override.ExpiresAt = time.Now().Add(ttl)
+	}
+	if err := rrl.saveOverride(ctx, userID, override); err != nil {
+		return err
+	}
+
+	// Сохраняем новый лимит в кэше, не дожидаясь оповещения из Redis
 	rrl.mu.Lock()
 	defer rrl.mu.Unlock()
-	rrl.userLimits[userID] = redis_rate.Limit{
-		Rate:   newRate,
-		Period: time.Second,
-		Burst:  newBurst,
-	}
+	rrl.prune(time.Now())
+	rrl.userLimits[userID] = override
 
 	return nil
 }
+
+// UserLimit возвращает действующий индивидуальный лимит пользователя.
+// ok == false, если для пользователя действуют лимиты по умолчанию.
+func (rrl *RedisRateLimiter) UserLimit(userID string) (Override, bool) {
+	o, ok := rrl.effective(userID)
+	if !ok {
+		return Override{}, false
+	}
+	return o, true
+}
+
+// UserLimits возвращает все действующие индивидуальные лимиты.
+func (rrl *RedisRateLimiter) UserLimits() map[string]Override {
+	rrl.mu.Lock()
+	defer rrl.mu.Unlock()
+	rrl.prune(time.Now())
+	return maps.Clone(rrl.userLimits)
+}
+
+// DeleteUserLimit удаляет индивидуальный лимит пользователя на всех экземплярах,
+// после чего для него действуют лимиты по умолчанию.
+// Возвращает удаленный лимит, прочитанный из Redis, или false,
+// если индивидуального лимита не было.
+func (rrl *RedisRateLimiter) DeleteUserLimit(ctx context.Context, userID string) (Override, bool, error) {
+	deleted, ok, err := rrl.removeOverride(ctx, userID)
+	if err != nil {
+		return Override{}, false, err
+	}
+
+	rrl.mu.Lock()
+	defer rrl.mu.Unlock()
+	delete(rrl.userLimits, userID)
+	return deleted, ok, nil
+}
+
+// Status возвращает действующий лимит пользователя и оставшиеся токены из Redis.
+// Токены при этом не расходуются.
+func (rrl *RedisRateLimiter) Status(ctx context.Context, userID string) (Status, error) {
+	current, isOverride := rrl.effective(userID)
+	status := Status{Rate: current.Rate, Burst: current.Burst, Override: isOverride, ExpiresAt: current.ExpiresAt}
+
+	res, err := rrl.limiter.AllowN(ctx, userID, current.limit(), 0)
+	if err != nil {
+		return Status{}, err
+	}
+	status.Remaining = res.Remaining
+	status.ResetAfter = res.ResetAfter
+	return status, nil
+}
+
+// prune удаляет истекшие индивидуальные лимиты. Вызывается под rrl.mu.
+func (rrl *RedisRateLimiter) prune(now time.Time) {
+	maps.DeleteFunc(rrl.userLimits, func(_ string, o Override) bool { return !o.active(now) })
+}
+
+// SetDefaultLimit заменяет лимиты по умолчанию для клиентов без индивидуальных лимитов.
+func (rrl *RedisRateLimiter) SetDefaultLimit(rate, burst int) {
+	rrl.mu.Lock()
+	defer rrl.mu.Unlock()
+	rrl.defaultRate = rate
+	rrl.defaultBurst = burst
+}
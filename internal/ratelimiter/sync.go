@@ -0,0 +1,148 @@
+package ratelimiter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Индивидуальные лимиты хранятся в Redis, чтобы переживать перезапуск
+// и действовать одинаково на всех экземплярах балансировщика.
+const (
+	overrideKeyPrefix = "ratelimit:override:" // Ключ лимита клиента: префикс + идентификатор
+	overridesChannel  = "ratelimit:overrides" // Канал с идентификаторами клиентов, чьи лимиты изменились
+)
+
+func overrideKey(userID string) string {
+	return overrideKeyPrefix + userID
+}
+
+// saveOverride сохраняет лимит в Redis и оповещает остальные экземпляры.
+// Лимит со сроком действия удаляется из Redis по его истечении.
+func (rrl *RedisRateLimiter) saveOverride(ctx context.Context, userID string, o Override) error {
+	data, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	var ttl time.Duration
+	if !o.ExpiresAt.IsZero() {
+		ttl = time.Until(o.ExpiresAt)
+	}
+	if err := rrl.rdb.Set(ctx, overrideKey(userID), data, ttl).Err(); err != nil {
+		return err
+	}
+	return rrl.rdb.Publish(ctx, overridesChannel, userID).Err()
+}
+
+// removeOverride удаляет лимит из Redis и оповещает остальные экземпляры.
+// Возвращает удаленный лимит или false, если лимита в Redis не было.
+func (rrl *RedisRateLimiter) removeOverride(ctx context.Context, userID string) (Override, bool, error) {
+	data, err := rrl.rdb.GetDel(ctx, overrideKey(userID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return Override{}, false, nil
+	}
+	if err != nil {
+		return Override{}, false, err
+	}
+	var o Override
+	if err := json.Unmarshal(data, &o); err != nil {
+		return Override{}, false, err
+	}
+	return o, true, rrl.rdb.Publish(ctx, overridesChannel, userID).Err()
+}
+
+// loadOverrides читает все индивидуальные лимиты из Redis и заменяет ими локальный кэш.
+func (rrl *RedisRateLimiter) loadOverrides(ctx context.Context, rdb *redis.Client) error {
+	overrides := make(map[string]Override)
+	iter := rdb.Scan(ctx, 0, overrideKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		o, ok, err := getOverride(ctx, rdb, key)
+		if err != nil {
+			return err
+		}
+		if ok {
+			overrides[strings.TrimPrefix(key, overrideKeyPrefix)] = o
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+
+	rrl.mu.Lock()
+	rrl.userLimits = overrides
+	rrl.mu.Unlock()
+	return nil
+}
+
+// refreshOverride перечитывает лимит клиента из Redis после оповещения об изменении.
+func (rrl *RedisRateLimiter) refreshOverride(ctx context.Context, rdb *redis.Client, userID string) error {
+	o, ok, err := getOverride(ctx, rdb, overrideKey(userID))
+	if err != nil {
+		return err
+	}
+
+	rrl.mu.Lock()
+	defer rrl.mu.Unlock()
+	if ok {
+		rrl.userLimits[userID] = o
+	} else {
+		delete(rrl.userLimits, userID)
+	}
+	return nil
+}
+
+// getOverride читает лимит по ключу. Возвращает false, если ключа нет.
+func getOverride(ctx context.Context, rdb *redis.Client, key string) (Override, bool, error) {
+	data, err := rdb.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return Override{}, false, nil
+	}
+	if err != nil {
+		return Override{}, false, err
+	}
+	var o Override
+	if err := json.Unmarshal(data, &o); err != nil {
+		return Override{}, false, err
+	}
+	return o, true, nil
+}
+
+// Run получает оповещения об изменении индивидуальных лимитов от других
+// экземпляров и обновляет локальный кэш до отмены ctx. После каждой
+// (пере)подписки кэш перечитывается целиком, чтобы не потерять изменения,
+// сделанные во время разрыва соединения с Redis.
+func (rrl *RedisRateLimiter) Run(ctx context.Context) {
+	rdb := rrl.rdb
+	pubsub := rdb.Subscribe(ctx, overridesChannel)
+	defer pubsub.Close()
+
+	messages := pubsub.ChannelWithSubscriptions()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-messages:
+			switch msg := msg.(type) {
+			case *redis.Subscription:
+				if err := rrl.loadOverrides(ctx, rdb); err != nil {
+					rrl.log.Error("Failed to load rate limit overrides", zap.Error(err))
+					continue
+				}
+				rrl.log.Debug("Rate limit overrides synchronized", zap.Int("overrides", len(rrl.UserLimits())))
+			case *redis.Message:
+				if err := rrl.refreshOverride(ctx, rdb, msg.Payload); err != nil {
+					rrl.log.Error("Failed to refresh rate limit override",
+						zap.String("identifier", msg.Payload),
+						zap.Error(err),
+					)
+				}
+			}
+		}
+	}
+}
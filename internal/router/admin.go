@@ -0,0 +1,327 @@
+package router
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/config"
+	"github.com/DblMOKRQ/cloud_test_task/internal/models"
+	"github.com/DblMOKRQ/cloud_test_task/internal/router/certs"
+	"github.com/DblMOKRQ/cloud_test_task/internal/router/errs"
+	"go.uber.org/zap"
+)
+
+// callerKey — ключ контекста с именем аутентифицированного вызывающего admin API.
+type callerKey struct{}
+
+// newAdminServer создает слушатель admin API. Все запросы к нему
+// проходят аутентификацию по bearer-токену и/или клиентскому сертификату.
+func (rt *Router) newAdminServer(cfg config.Admin) (*http.Server, error) {
+	tlsCfg, err := certs.AdminConfig(cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("admin: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/edit", rt.HandleEdit)
+	mux.HandleFunc("/admin/limits", rt.HandleLimits)
+	mux.HandleFunc("/admin/breakers", rt.HandleBreakers)
+	mux.HandleFunc("/admin/backends", rt.HandleBackends)
+	mux.HandleFunc("/admin/backends/drain", rt.HandleDrain)
+
+	return &http.Server{
+		Addr:              cfg.Address,
+		Handler:           rt.authenticate(cfg.Tokens, mux),
+		TLSConfig:         tlsCfg,
+		ReadHeaderTimeout: 10 * time.Second,
+	}, nil
+}
+
+// serveAdmin принимает соединения admin API на TCP-адресе или unix-сокете.
+func (rt *Router) serveAdmin() error {
+	cfg := rt.cfg.Admin
+	var (
+		ln  net.Listener
+		err error
+	)
+	if cfg.Socket != "" {
+		// Сокет, оставшийся от прошлого запуска, мешает слушать тот же путь
+		if err := os.Remove(cfg.Socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if ln, err = net.Listen("unix", cfg.Socket); err != nil {
+			return err
+		}
+		if err := os.Chmod(cfg.Socket, 0o600); err != nil {
+			ln.Close()
+			return err
+		}
+	} else if ln, err = net.Listen("tcp", cfg.Address); err != nil {
+		return err
+	}
+
+	rt.log.Info("Starting admin server",
+		zap.String("address", ln.Addr().String()),
+		zap.Bool("tls", rt.admin.TLSConfig != nil),
+		zap.Bool("mtls", cfg.TLS.ClientCAFile != ""),
+	)
+	if rt.admin.TLSConfig != nil {
+		return rt.admin.ServeTLS(ln, "", "")
+	}
+	return rt.admin.Serve(ln)
+}
+
+// authenticate пропускает запросы с верным bearer-токеном, если токены заданы.
+// Клиентский сертификат при mTLS проверяется при установке соединения.
+// Имя вызывающего сохраняется в контексте для журнала аудита.
+func (rt *Router) authenticate(tokens map[string]string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var callers []string
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+			callers = append(callers, "cert:"+r.TLS.VerifiedChains[0][0].Subject.CommonName)
+		}
+		if len(tokens) > 0 {
+			name, ok := matchToken(tokens, r.Header.Get("Authorization"))
+			if !ok {
+				rt.log.Warn("Admin authentication failed",
+					zap.String("remote_addr", r.RemoteAddr),
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path),
+				)
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				errs.JSONError(w, errs.ErrorResponse{Error: "Unauthorized"}, http.StatusUnauthorized)
+				return
+			}
+			callers = append(callers, "token:"+name)
+		}
+		ctx := context.WithValue(r.Context(), callerKey{}, strings.Join(callers, " "))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// matchToken ищет имя токена из заголовка Authorization.
+// Сравнение выполняется за постоянное время и не прерывается на совпадении.
+func matchToken(tokens map[string]string, header string) (string, bool) {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(token))
+	var matched string
+	for name, expected := range tokens {
+		want := sha256.Sum256([]byte(expected))
+		if subtle.ConstantTimeCompare(sum[:], want[:]) == 1 {
+			matched = name
+		}
+	}
+	return matched, matched != ""
+}
+
+// audit записывает в журнал аудита изменение, сделанное через admin API:
+// кто его сделал, над чем, значения до и после.
+func (rt *Router) audit(r *http.Request, action, target string, before, after any) {
+	caller, _ := r.Context().Value(callerKey{}).(string)
+	rt.log.Info("Admin audit",
+		zap.String("caller", caller),
+		zap.String("remote_addr", r.RemoteAddr),
+		zap.String("action", action),
+		zap.String("target", target),
+		zap.Any("old", before),
+		zap.Any("new", after),
+	)
+}
+
+// backendInfo описывает backend-сервер в ответах admin API.
+type backendInfo struct {
+	Pool     string `json:"pool"`
+	URL      string `json:"url"`
+	Weight   int    `json:"weight"`
+	Alive    bool   `json:"alive"`
+	Draining bool   `json:"draining"`
+	InFlight int64  `json:"in_flight"`
+	Breaker  string `json:"breaker"`
+}
+
+func newBackendInfo(pool string, server *models.Server) backendInfo {
+	breaker := "disabled"
+	if server.Breaker != nil {
+		breaker = server.Breaker.State()
+	}
+	return backendInfo{
+		Pool:     pool,
+		URL:      server.URL.String(),
+		Weight:   server.Weight,
+		Alive:    server.IsAlive(),
+		Draining: server.IsDraining(),
+		InFlight: server.InFlight(),
+		Breaker:  breaker,
+	}
+}
+
+// backendRequest — тело запросов на добавление и вывод backend-а из балансировки.
+type backendRequest struct {
+	Pool   string `json:"pool"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// HandleBackends обрабатывает запросы к списку backend-ов:
+// GET — список серверов, POST — добавление, DELETE — удаление.
+func (rt *Router) HandleBackends(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		rt.listBackends(w)
+	case http.MethodPost:
+		var request backendRequest
+		if !rt.decodeBackendRequest(w, r, &request) {
+			return
+		}
+		if request.Weight < 0 {
+			errs.JSONError(w, errs.ErrorResponse{Error: "weight must not be negative"}, http.StatusBadRequest)
+			return
+		}
+		p, ok := rt.lookupPool(w, request.Pool)
+		if !ok {
+			return
+		}
+		server, err := p.addServer(config.Backend{URL: request.URL, Weight: request.Weight})
+		if err != nil {
+			rt.backendError(w, err)
+			return
+		}
+		info := newBackendInfo(p.name, server)
+		rt.audit(r, "backend.add", p.name+" "+info.URL, nil, info)
+		writeJSON(w, info, http.StatusCreated)
+	case http.MethodDelete:
+		request := backendRequest{Pool: r.URL.Query().Get("pool"), URL: r.URL.Query().Get("url")}
+		if !rt.validateBackendRequest(w, &request) {
+			return
+		}
+		p, ok := rt.lookupPool(w, request.Pool)
+		if !ok {
+			return
+		}
+		server, err := p.removeServer(request.URL)
+		if err != nil {
+			rt.backendError(w, err)
+			return
+		}
+		rt.audit(r, "backend.remove", p.name+" "+request.URL, newBackendInfo(p.name, server), nil)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		errs.JSONError(w, errs.ErrorResponse{Error: "Only GET, POST and DELETE methods are allowed"}, http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleDrain обрабатывает вывод backend-а из балансировки:
+// POST — новые запросы на сервер не направляются, DELETE — сервер возвращается.
+func (rt *Router) HandleDrain(w http.ResponseWriter, r *http.Request) {
+	var request backendRequest
+	switch r.Method {
+	case http.MethodPost:
+		if !rt.decodeBackendRequest(w, r, &request) {
+			return
+		}
+	case http.MethodDelete:
+		request = backendRequest{Pool: r.URL.Query().Get("pool"), URL: r.URL.Query().Get("url")}
+		if !rt.validateBackendRequest(w, &request) {
+			return
+		}
+	default:
+		errs.JSONError(w, errs.ErrorResponse{Error: "Only POST and DELETE methods are allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	p, ok := rt.lookupPool(w, request.Pool)
+	if !ok {
+		return
+	}
+	draining := r.Method == http.MethodPost
+	server, was, err := p.drain(request.URL, draining)
+	if err != nil {
+		rt.backendError(w, err)
+		return
+	}
+	rt.audit(r, "backend.drain", p.name+" "+request.URL, map[string]bool{"draining": was}, map[string]bool{"draining": draining})
+	writeJSON(w, newBackendInfo(p.name, server), http.StatusOK)
+}
+
+func (rt *Router) listBackends(w http.ResponseWriter) {
+	backends := make([]backendInfo, 0)
+	for _, p := range rt.state.Load().sortedPools() {
+		for _, server := range p.list() {
+			backends = append(backends, newBackendInfo(p.name, server))
+		}
+	}
+	writeJSON(w, backends, http.StatusOK)
+}
+
+// decodeBackendRequest читает и проверяет JSON-тело запроса.
+// При ошибке отправляет ответ клиенту и возвращает false.
+func (rt *Router) decodeBackendRequest(w http.ResponseWriter, r *http.Request, request *backendRequest) bool {
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		rt.log.Error("Failed to decode request", zap.Error(err))
+		errs.JSONError(w, errs.ErrorResponse{Error: "Invalid request format"}, http.StatusBadRequest)
+		return false
+	}
+	return rt.validateBackendRequest(w, request)
+}
+
+// validateBackendRequest проверяет URL backend-а и приводит его к каноническому виду.
+// Пул по умолчанию — default.
+func (rt *Router) validateBackendRequest(w http.ResponseWriter, request *backendRequest) bool {
+	if request.Pool == "" {
+		request.Pool = config.DefaultPool
+	}
+	if request.URL == "" {
+		errs.JSONError(w, errs.ErrorResponse{Error: "url is required"}, http.StatusBadRequest)
+		return false
+	}
+	u, err := url.Parse(request.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs.JSONError(w, errs.ErrorResponse{Error: "url must be an absolute http or https URL"}, http.StatusBadRequest)
+		return false
+	}
+	request.URL = u.String()
+	return true
+}
+
+// lookupPool возвращает пул по имени.
+// Если пула нет, отправляет 404 и возвращает false.
+func (rt *Router) lookupPool(w http.ResponseWriter, name string) (*pool, bool) {
+	p, ok := rt.state.Load().pools[name]
+	if !ok {
+		errs.JSONError(w, errs.ErrorResponse{Error: fmt.Sprintf("pool %q not found", name)}, http.StatusNotFound)
+	}
+	return p, ok
+}
+
+// backendError отправляет ответ на ошибку изменения backend-а.
+func (rt *Router) backendError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errBackendExists):
+		errs.JSONError(w, errs.ErrorResponse{Error: err.Error()}, http.StatusConflict)
+	case errors.Is(err, errBackendNotFound):
+		errs.JSONError(w, errs.ErrorResponse{Error: err.Error()}, http.StatusNotFound)
+	default:
+		rt.log.Error("Failed to update backend", zap.Error(err))
+		errs.JSONError(w, errs.ErrorResponse{Error: err.Error()}, http.StatusBadRequest)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any, code int) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -2,22 +2,52 @@ package balancer
 
 import (
 	"errors"
+	"net/http"
 
+	"github.com/DblMOKRQ/cloud_test_task/internal/config"
 	"github.com/DblMOKRQ/cloud_test_task/internal/models"
+	consistenthash "github.com/DblMOKRQ/cloud_test_task/internal/router/backend/balancer/consistent_hash"
+	leastconn "github.com/DblMOKRQ/cloud_test_task/internal/router/backend/balancer/least_conn"
+	p2cewma "github.com/DblMOKRQ/cloud_test_task/internal/router/backend/balancer/p2c_ewma"
 	random "github.com/DblMOKRQ/cloud_test_task/internal/router/backend/balancer/random_distribution"
 	roundrobin "github.com/DblMOKRQ/cloud_test_task/internal/router/backend/balancer/round_robin"
+	"github.com/DblMOKRQ/cloud_test_task/internal/router/backend/balancer/sticky"
+	weightedroundrobin "github.com/DblMOKRQ/cloud_test_task/internal/router/backend/balancer/weighted_round_robin"
 )
 
 type balancer interface {
-	Next() *models.Server
+	Next(r *http.Request) *models.Server
+	SetServers(servers []*models.Server)
 }
 
-func GetAlgorithm(algorithm string, servers []*models.Server) (balancer, error) {
-	switch algorithm {
+// GetAlgorithm создает балансировщик по настройкам из конфига.
+// При включенных sticky-сессиях оборачивает его привязкой клиентов к серверам.
+// Возвращает ошибку для неизвестного алгоритма.
+func GetAlgorithm(cfg config.Balancer, servers []*models.Server) (balancer, error) {
+	bal, err := newAlgorithm(cfg, servers)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Sticky.Enabled {
+		return sticky.NewSticky(bal, servers, cfg.Sticky)
+	}
+	return bal, nil
+}
+
+func newAlgorithm(cfg config.Balancer, servers []*models.Server) (balancer, error) {
+	switch cfg.Algorithm {
 	case "roundrobin":
 		return roundrobin.NewRoundRobin(servers)
 	case "random":
 		return random.NewRandom(servers)
+	case "leastconn":
+		return leastconn.NewLeastConn(servers)
+	case "weighted_roundrobin":
+		return weightedroundrobin.NewWeightedRoundRobin(servers)
+	case "p2c_ewma":
+		return p2cewma.NewP2CEWMA(servers)
+	case "consistent_hash":
+		return consistenthash.NewConsistentHash(servers, cfg.Hash)
 	default:
 		return nil, errors.New("invalid algorithm")
 	}
@@ -0,0 +1,135 @@
+package consistenthash
+
+import (
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/config"
+	"github.com/DblMOKRQ/cloud_test_task/internal/models"
+	"github.com/DblMOKRQ/cloud_test_task/internal/router/forwarded"
+)
+
+// node — виртуальный узел на кольце хешей.
+type node struct {
+	hash   uint64
+	server *models.Server
+}
+
+// ConsistentHash реализует балансировку консистентным хешированием
+// с виртуальными узлами. Ключ берется из IP клиента, заголовка или cookie.
+type ConsistentHash struct {
+	servers  []*models.Server // Список серверов
+	ring     []node           // Виртуальные узлы, отсортированные по хешу
+	key      string           // Источник ключа: ip, header или cookie
+	name     string           // Имя заголовка или cookie
+	replicas int              // Количество виртуальных узлов на единицу веса
+	mu       sync.RWMutex     // Мьютекс для безопасного обновления кольца
+}
+
+// NewConsistentHash создает новый экземпляр балансировщика ConsistentHash.
+// Количество виртуальных узлов сервера пропорционально его весу.
+func NewConsistentHash(servers []*models.Server, cfg config.Hash) (*ConsistentHash, error) {
+	replicas := cfg.Replicas
+	if replicas <= 0 {
+		replicas = 100
+	}
+	ch := &ConsistentHash{
+		servers:  servers,
+		key:      cfg.Key,
+		name:     cfg.Name,
+		replicas: replicas,
+	}
+	ch.build()
+	return ch, nil
+}
+
+// SetServers заменяет список серверов и перестраивает кольцо.
+// Переезжают только ключи добавленных и удаленных серверов.
+func (ch *ConsistentHash) SetServers(servers []*models.Server) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.servers = servers
+	ch.build()
+}
+
+// build заполняет кольцо виртуальными узлами всех серверов.
+// Вызывается при создании или под ch.mu.
+func (ch *ConsistentHash) build() {
+	ring := make([]node, 0, len(ch.servers)*ch.replicas)
+	for _, server := range ch.servers {
+		weight := server.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		for i := 0; i < ch.replicas*weight; i++ {
+			ring = append(ring, node{
+				hash:   hashKey(server.URL.String() + "#" + strconv.Itoa(i)),
+				server: server,
+			})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+	ch.ring = ring
+}
+
+// Next возвращает сервер, за которым закреплен ключ запроса.
+// Недоступные серверы пропускаются по часовой стрелке, поэтому при падении
+// сервера на другие узлы переезжают только его ключи.
+// Возвращает nil, если нет доступных серверов.
+func (ch *ConsistentHash) Next(r *http.Request) *models.Server {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+
+	if len(ch.ring) == 0 {
+		return nil
+	}
+
+	h := hashKey(ch.requestKey(r))
+	start := sort.Search(len(ch.ring), func(i int) bool { return ch.ring[i].hash >= h })
+
+	checked := make(map[*models.Server]struct{}, len(ch.servers))
+	for i := 0; i < len(ch.ring) && len(checked) < len(ch.servers); i++ {
+		server := ch.ring[(start+i)%len(ch.ring)].server
+		if _, ok := checked[server]; ok {
+			continue
+		}
+		if server.Available(r) {
+			return server
+		}
+		checked[server] = struct{}{}
+	}
+	return nil
+}
+
+// requestKey извлекает ключ хеширования из запроса.
+// Если заголовок или cookie отсутствуют, используется IP клиента.
+func (ch *ConsistentHash) requestKey(r *http.Request) string {
+	switch ch.key {
+	case "header":
+		if v := r.Header.Get(ch.name); v != "" {
+			return v
+		}
+	case "cookie":
+		if c, err := r.Cookie(ch.name); err == nil && c.Value != "" {
+			return c.Value
+		}
+	}
+	return forwarded.ClientIP(r)
+}
+
+// hashKey хеширует ключ FNV-1a с финальным перемешиванием битов,
+// чтобы близкие ключи виртуальных узлов равномерно ложились на кольцо.
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
@@ -0,0 +1,58 @@
+package leastconn
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/models"
+)
+
+// LeastConn реализует алгоритм балансировки по наименьшему числу активных запросов.
+type LeastConn struct {
+	servers []*models.Server // Список серверов
+	start   uint32           // Смещение начала обхода для равномерного выбора среди равных (атомарное)
+	mu      sync.RWMutex     // Мьютекс для безопасного обновления списка серверов
+}
+
+// NewLeastConn создает новый экземпляр балансировщика LeastConn.
+// Принимает список серверов для балансировки нагрузки.
+func NewLeastConn(servers []*models.Server) (*LeastConn, error) {
+	return &LeastConn{servers: servers}, nil
+}
+
+// SetServers заменяет список серверов для балансировки.
+func (lc *LeastConn) SetServers(servers []*models.Server) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.servers = servers
+}
+
+// Next возвращает доступный сервер с наименьшим числом запросов в обработке.
+// При равной нагрузке серверы выбираются по очереди.
+// Возвращает nil, если нет доступных серверов.
+func (lc *LeastConn) Next(r *http.Request) *models.Server {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+
+	n := uint32(len(lc.servers))
+	if n == 0 {
+		return nil
+	}
+
+	start := atomic.AddUint32(&lc.start, 1)
+	var best *models.Server
+	var bestLoad int64
+	for i := uint32(0); i < n; i++ {
+		server := lc.servers[(start+i)%n]
+		if !server.Available(r) {
+			continue
+		}
+		load := server.InFlight()
+		if best == nil || load < bestLoad {
+			best = server
+			bestLoad = load
+		}
+	}
+	return best
+}
@@ -0,0 +1,68 @@
+package p2cewma
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"sync"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/models"
+)
+
+// P2CEWMA реализует алгоритм "power of two choices": из двух случайных
+// доступных серверов выбирается тот, у которого меньше ожидаемая стоимость
+// запроса — среднее время ответа с учетом запросов в обработке.
+type P2CEWMA struct {
+	servers []*models.Server // Список серверов
+	mu      sync.RWMutex     // Мьютекс для безопасного обновления списка серверов
+}
+
+// NewP2CEWMA создает новый экземпляр балансировщика P2CEWMA.
+// Принимает список серверов для балансировки нагрузки.
+func NewP2CEWMA(servers []*models.Server) (*P2CEWMA, error) {
+	return &P2CEWMA{servers: servers}, nil
+}
+
+// SetServers заменяет список серверов для балансировки.
+func (p *P2CEWMA) SetServers(servers []*models.Server) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.servers = servers
+}
+
+// Next возвращает менее нагруженный из двух случайных доступных серверов.
+// Возвращает nil, если нет доступных серверов.
+func (p *P2CEWMA) Next(r *http.Request) *models.Server {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	alive := make([]*models.Server, 0, len(p.servers))
+	for _, server := range p.servers {
+		if server.Available(r) {
+			alive = append(alive, server)
+		}
+	}
+
+	switch len(alive) {
+	case 0:
+		return nil
+	case 1:
+		return alive[0]
+	}
+
+	i := rand.IntN(len(alive))
+	j := rand.IntN(len(alive) - 1)
+	if j >= i {
+		j++
+	}
+	a, b := alive[i], alive[j]
+	if cost(b) < cost(a) {
+		return b
+	}
+	return a
+}
+
+// cost оценивает стоимость отправки запроса на сервер.
+// Серверы без замеров считаются самыми дешевыми, чтобы на них попадал трафик.
+func cost(s *models.Server) float64 {
+	return float64(s.Latency()) * float64(s.InFlight()+1)
+}
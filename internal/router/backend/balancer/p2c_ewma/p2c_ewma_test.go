@@ -0,0 +1,43 @@
+package p2cewma
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/models"
+)
+
+func newServer(host string) *models.Server {
+	return &models.Server{URL: &url.URL{Scheme: "http", Host: host}, Alive: true, Weight: 1}
+}
+
+// Сервер, быстро отвечающий ошибками, не должен выглядеть самым дешевым.
+func TestFastFailingServerNotPreferred(t *testing.T) {
+	dead := newServer("dead:80")
+	live := []*models.Server{newServer("a:80"), newServer("b:80")}
+	dead.ObserveFailure(500 * time.Microsecond)
+	for _, s := range live {
+		s.ObserveLatency(20 * time.Millisecond)
+	}
+
+	p, _ := NewP2CEWMA(append([]*models.Server{dead}, live...))
+	r := httptest.NewRequest("GET", "/", nil)
+	for i := 0; i < 60; i++ {
+		if p.Next(r) == dead {
+			t.Fatalf("request %d went to the failing server (latency %v vs %v)", i, dead.Latency(), live[0].Latency())
+		}
+	}
+}
+
+func TestUnmeasuredServerPreferred(t *testing.T) {
+	fresh := newServer("fresh:80")
+	slow := newServer("slow:80")
+	slow.ObserveLatency(20 * time.Millisecond)
+
+	p, _ := NewP2CEWMA([]*models.Server{fresh, slow})
+	if got := p.Next(httptest.NewRequest("GET", "/", nil)); got != fresh {
+		t.Fatalf("Next() = %s, want server without samples", got.URL)
+	}
+}
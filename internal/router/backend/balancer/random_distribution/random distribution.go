@@ -2,6 +2,7 @@ package randomdistribution
 
 import (
 	"math/rand"
+	"net/http"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -25,8 +26,15 @@ func NewRandom(servers []*models.Server) (*Random, error) {
 	return r, nil
 }
 
+// SetServers заменяет список серверов для балансировки.
+func (r *Random) SetServers(servers []*models.Server) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.servers = servers
+}
+
 // Next возвращает случайный доступный сервер.
-func (r *Random) Next() *models.Server {
+func (r *Random) Next(req *http.Request) *models.Server {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -47,11 +55,7 @@ func (r *Random) Next() *models.Server {
 		currentIndex := (index + i) % len(r.servers)
 		server := r.servers[currentIndex]
 
-		server.Mu.RLock()
-		alive := server.Alive
-		server.Mu.RUnlock()
-
-		if alive {
+		if server.Available(req) {
 			return server
 		}
 	}
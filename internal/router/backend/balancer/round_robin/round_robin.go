@@ -1,6 +1,7 @@
 package roundrobin
 
 import (
+	"net/http"
 	"sync"
 	"sync/atomic"
 
@@ -22,9 +23,16 @@ func NewRoundRobin(servers []*models.Server) (*RoundRobin, error) {
 	return rr, nil
 }
 
+// SetServers заменяет список серверов для балансировки.
+func (rr *RoundRobin) SetServers(servers []*models.Server) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	rr.servers = servers
+}
+
 // Next возвращает следующий доступный сервер из списка.
 // Возвращает nil, если нет доступных серверов.
-func (rr *RoundRobin) Next() *models.Server {
+func (rr *RoundRobin) Next(r *http.Request) *models.Server {
 	rr.mu.RLock()
 	defer rr.mu.RUnlock()
 
@@ -34,7 +42,7 @@ func (rr *RoundRobin) Next() *models.Server {
 
 	index := atomic.AddUint32(&rr.index, 1) % uint32(len(rr.servers))
 	for i := uint32(0); i < uint32(len(rr.servers)); i++ {
-		if rr.servers[index%uint32(len(rr.servers))].Alive {
+		if rr.servers[index%uint32(len(rr.servers))].Available(r) {
 			return rr.servers[index%uint32(len(rr.servers))]
 		}
 		index = (index + 1) % uint32(len(rr.servers))
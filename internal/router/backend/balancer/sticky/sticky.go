@@ -0,0 +1,138 @@
+package sticky
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/config"
+	"github.com/DblMOKRQ/cloud_test_task/internal/models"
+)
+
+type balancer interface {
+	Next(r *http.Request) *models.Server
+	SetServers(servers []*models.Server)
+}
+
+// Sticky добавляет к балансировщику привязку клиента к серверу через
+// подписанную cookie. Пока сервер из cookie жив, запросы идут на него.
+type Sticky struct {
+	next    balancer                  // Балансировщик для запросов без привязки
+	servers map[string]*models.Server // Серверы по идентификатору из cookie
+	cookie  string                    // Имя cookie
+	secret  []byte                    // Ключ подписи cookie
+	ttl     time.Duration             // Время жизни cookie, 0 — до закрытия браузера
+	mu      sync.RWMutex              // Мьютекс для безопасного обновления списка серверов
+}
+
+// NewSticky оборачивает балансировщик привязкой сессий.
+// Принимает список серверов, среди которых ищется сервер из cookie.
+func NewSticky(next balancer, servers []*models.Server, cfg config.Sticky) (*Sticky, error) {
+	s := &Sticky{
+		next:    next,
+		servers: make(map[string]*models.Server, len(servers)),
+		cookie:  cfg.Cookie,
+		secret:  []byte(cfg.Secret),
+		ttl:     cfg.TTL,
+	}
+	for _, server := range servers {
+		s.servers[s.id(server)] = server
+	}
+	return s, nil
+}
+
+// SetServers заменяет список серверов. Cookie удаленных серверов
+// перестают действовать, такие клиенты получают новую привязку.
+func (s *Sticky) SetServers(servers []*models.Server) {
+	byID := make(map[string]*models.Server, len(servers))
+	for _, server := range servers {
+		byID[s.id(server)] = server
+	}
+
+	s.mu.Lock()
+	s.servers = byID
+	s.mu.Unlock()
+	s.next.SetServers(servers)
+}
+
+// Next возвращает сервер из cookie, если подпись верна и сервер доступен.
+// Иначе выбирает сервер обернутым балансировщиком.
+func (s *Sticky) Next(r *http.Request) *models.Server {
+	if server := s.lookup(r); server != nil && server.Available(r) {
+		return server
+	}
+	return s.next.Next(r)
+}
+
+// Bind устанавливает cookie привязки к выбранному серверу,
+// если запрос еще не содержит такую cookie.
+func (s *Sticky) Bind(w http.ResponseWriter, r *http.Request, server *models.Server) {
+	if server == nil || s.lookup(r) == server {
+		return
+	}
+
+	cookie := &http.Cookie{
+		Name:     s.cookie,
+		Value:    s.sign(s.id(server)),
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if s.ttl > 0 {
+		cookie.MaxAge = int(s.ttl.Seconds())
+	}
+	http.SetCookie(w, cookie)
+}
+
+// lookup возвращает сервер из cookie запроса или nil,
+// если cookie нет, подпись неверна или сервер неизвестен.
+func (s *Sticky) lookup(r *http.Request) *models.Server {
+	c, err := r.Cookie(s.cookie)
+	if err != nil {
+		return nil
+	}
+	id, ok := s.verify(c.Value)
+	if !ok {
+		return nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.servers[id]
+}
+
+// id возвращает непрозрачный идентификатор сервера для cookie, чтобы
+// клиенты не видели внутренние адреса. Идентификатор зависит от ключа
+// подписи, поэтому одинаков на всех экземплярах с тем же secret.
+func (s *Sticky) id(server *models.Server) string {
+	return base64.RawURLEncoding.EncodeToString(s.mac("server:" + server.URL.String())[:12])
+}
+
+// sign добавляет к идентификатору сервера HMAC-подпись.
+func (s *Sticky) sign(id string) string {
+	return id + "." + base64.RawURLEncoding.EncodeToString(s.mac(id))
+}
+
+// verify проверяет подпись значения cookie и возвращает идентификатор сервера.
+func (s *Sticky) verify(value string) (string, bool) {
+	id, signature, found := strings.Cut(value, ".")
+	if !found {
+		return "", false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, s.mac(id)) {
+		return "", false
+	}
+	return id, true
+}
+
+func (s *Sticky) mac(payload string) []byte {
+	m := hmac.New(sha256.New, s.secret)
+	m.Write([]byte(payload))
+	return m.Sum(nil)
+}
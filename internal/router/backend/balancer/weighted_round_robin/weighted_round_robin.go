@@ -0,0 +1,69 @@
+package weightedroundrobin
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/models"
+)
+
+// WeightedRoundRobin реализует алгоритм плавного взвешенного Round Robin (как в nginx).
+type WeightedRoundRobin struct {
+	servers []*models.Server // Список серверов
+	current []int            // Текущие веса серверов
+	mu      sync.Mutex       // Мьютекс для защиты текущих весов и списка серверов
+}
+
+// NewWeightedRoundRobin создает новый экземпляр балансировщика WeightedRoundRobin.
+// Принимает список серверов с заданными весами.
+func NewWeightedRoundRobin(servers []*models.Server) (*WeightedRoundRobin, error) {
+	return &WeightedRoundRobin{
+		servers: servers,
+		current: make([]int, len(servers)),
+	}, nil
+}
+
+// SetServers заменяет список серверов для балансировки.
+// Текущие веса оставшихся серверов сохраняются.
+func (wrr *WeightedRoundRobin) SetServers(servers []*models.Server) {
+	wrr.mu.Lock()
+	defer wrr.mu.Unlock()
+
+	prev := make(map[*models.Server]int, len(wrr.servers))
+	for i, server := range wrr.servers {
+		prev[server] = wrr.current[i]
+	}
+	current := make([]int, len(servers))
+	for i, server := range servers {
+		current[i] = prev[server]
+	}
+	wrr.servers = servers
+	wrr.current = current
+}
+
+// Next возвращает следующий доступный сервер с учетом весов.
+// Серверы с большим весом выбираются чаще, но не подряд.
+// Возвращает nil, если нет доступных серверов.
+func (wrr *WeightedRoundRobin) Next(r *http.Request) *models.Server {
+	wrr.mu.Lock()
+	defer wrr.mu.Unlock()
+
+	best := -1
+	total := 0
+	for i, server := range wrr.servers {
+		if !server.Available(r) {
+			continue
+		}
+		wrr.current[i] += server.Weight
+		total += server.Weight
+		if best == -1 || wrr.current[i] > wrr.current[best] {
+			best = i
+		}
+	}
+	if best == -1 {
+		return nil
+	}
+
+	wrr.current[best] -= total
+	return wrr.servers[best]
+}
@@ -0,0 +1,203 @@
+package circuitbreaker
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/config"
+	"github.com/DblMOKRQ/cloud_test_task/internal/models"
+	logger "github.com/DblMOKRQ/cloud_test_task/pkg"
+	"go.uber.org/zap"
+)
+
+type state int
+
+const (
+	closed state = iota
+	open
+	halfOpen
+)
+
+func (s state) String() string {
+	switch s {
+	case open:
+		return "open"
+	case halfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// Breaker реализует circuit breaker для одного backend-сервера.
+// В состоянии closed считает долю ошибок и медленных ответов в окне,
+// в open не пропускает запросы, в half-open пропускает ограниченное
+// число пробных запросов.
+type Breaker struct {
+	name string
+	cfg  config.CircuitBreaker
+	log  *logger.Logger
+
+	mu          sync.Mutex
+	state       state
+	windowStart time.Time // Начало текущего окна статистики
+	requests    int       // Запросов в текущем окне
+	failures    int       // Ошибок и медленных ответов в текущем окне
+	openedAt    time.Time // Время перехода в open
+	trials      int       // Пробных запросов в обработке (half-open)
+	successes   int       // Успешных пробных запросов (half-open)
+}
+
+// New создает circuit breaker в состоянии closed.
+// Имя используется в логах при смене состояния.
+func New(name string, cfg config.CircuitBreaker, log *logger.Logger) *Breaker {
+	return &Breaker{
+		name:        name,
+		cfg:         cfg,
+		log:         log,
+		windowStart: time.Now(),
+	}
+}
+
+// Attach создает circuit breaker для каждого сервера.
+func Attach(servers []*models.Server, cfg config.CircuitBreaker, log *logger.Logger) {
+	for _, server := range servers {
+		server.Breaker = New(server.URL.String(), cfg, log)
+	}
+}
+
+// Permits сообщает, может ли балансировщик выбрать сервер.
+// Не изменяет состояние breaker-а.
+func (b *Breaker) Permits() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case open:
+		return time.Since(b.openedAt) >= b.cfg.OpenTimeout
+	case halfOpen:
+		return b.trials < b.cfg.HalfOpenRequests
+	default:
+		return true
+	}
+}
+
+// Allow резервирует отправку запроса на сервер. По истечении open_timeout
+// переводит breaker в half-open. Каждому успешному вызову Allow должен
+// соответствовать вызов Done или Cancel.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == open {
+		if time.Since(b.openedAt) < b.cfg.OpenTimeout {
+			return false
+		}
+		b.setState(halfOpen, "open timeout expired")
+	}
+	if b.state == halfOpen {
+		if b.trials >= b.cfg.HalfOpenRequests {
+			return false
+		}
+		b.trials++
+	}
+	return true
+}
+
+// Done учитывает результат запроса. Ответ дольше latency_threshold
+// считается ошибкой.
+func (b *Breaker) Done(failed bool, latency time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.cfg.LatencyThreshold > 0 && latency > b.cfg.LatencyThreshold {
+		failed = true
+	}
+
+	now := time.Now()
+	switch b.state {
+	case halfOpen:
+		b.releaseTrial()
+		if failed {
+			b.setState(open, "trial request failed")
+			return
+		}
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenRequests {
+			b.setState(closed, fmt.Sprintf("%d trial requests succeeded", b.successes))
+		}
+	case closed:
+		if now.Sub(b.windowStart) > b.cfg.Window {
+			b.resetWindow(now)
+		}
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.cfg.MinRequests {
+			rate := float64(b.failures) / float64(b.requests)
+			if rate >= b.cfg.ErrorRate {
+				b.setState(open, fmt.Sprintf("error rate %.2f over %d requests", rate, b.requests))
+			}
+		}
+	}
+}
+
+// Cancel освобождает резерв запроса, результат которого не учитывается
+// (например, клиент закрыл соединение).
+func (b *Breaker) Cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == halfOpen {
+		b.releaseTrial()
+	}
+}
+
+// State возвращает текущее состояние: closed, open или half-open.
+func (b *Breaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state.String()
+}
+
+func (b *Breaker) releaseTrial() {
+	if b.trials > 0 {
+		b.trials--
+	}
+}
+
+func (b *Breaker) resetWindow(now time.Time) {
+	b.windowStart = now
+	b.requests = 0
+	b.failures = 0
+}
+
+// setState переводит breaker в новое состояние и логирует причину.
+// Вызывается под b.mu.
+func (b *Breaker) setState(s state, reason string) {
+	now := time.Now()
+	prev := b.state
+	b.state = s
+	switch s {
+	case open:
+		b.openedAt = now
+	case halfOpen:
+		b.trials = 0
+		b.successes = 0
+	case closed:
+		b.resetWindow(now)
+	}
+
+	log := b.log.Info
+	if s == open {
+		log = b.log.Warn
+	}
+	log("Circuit breaker state changed",
+		zap.String("backend", b.name),
+		zap.String("from", prev.String()),
+		zap.String("to", s.String()),
+		zap.String("reason", reason),
+	)
+}
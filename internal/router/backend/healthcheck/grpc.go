@@ -0,0 +1,75 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// grpcProbe вызывает стандартный метод grpc.health.v1.Health/Check.
+// Соединение создается при первой проверке и переиспользуется.
+type grpcProbe struct {
+	service string      // Имя проверяемого сервиса, пустое — сервер целиком
+	tls     *tls.Config // Настройки TLS для схемы https, nil — по умолчанию
+	conn    *grpc.ClientConn
+	mu      sync.Mutex
+}
+
+func (p *grpcProbe) probe(ctx context.Context, target *url.URL) error {
+	conn, err := p.connect(target)
+	if err != nil {
+		return err
+	}
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: p.service})
+	if err != nil {
+		return err
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("unexpected grpc health status %s", resp.GetStatus())
+	}
+	return nil
+}
+
+// connect возвращает соединение с сервером, создавая его при необходимости.
+// Для схемы https используется TLS.
+func (p *grpcProbe) connect(target *url.URL) (*grpc.ClientConn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn != nil {
+		return p.conn, nil
+	}
+
+	creds := insecure.NewCredentials()
+	if target.Scheme == "https" {
+		tlsCfg := p.tls
+		if tlsCfg == nil {
+			tlsCfg = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		creds = credentials.NewTLS(tlsCfg)
+	}
+	conn, err := grpc.NewClient(hostPort(target), grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create grpc client: %v", err)
+	}
+	p.conn = conn
+	return conn, nil
+}
+
+func (p *grpcProbe) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn != nil {
+		_ = p.conn.Close()
+		p.conn = nil
+	}
+}
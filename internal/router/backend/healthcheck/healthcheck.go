@@ -2,9 +2,16 @@ package healthcheck
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
+	"math/rand/v2"
+	"net"
 	"net/http"
+	"slices"
+	"sync"
 	"time"
 
+	"github.com/DblMOKRQ/cloud_test_task/internal/config"
 	"github.com/DblMOKRQ/cloud_test_task/internal/models"
 	logger "github.com/DblMOKRQ/cloud_test_task/pkg"
 	"go.uber.org/zap"
@@ -12,21 +19,80 @@ import (
 
 // HealthChecker реализует проверку состояния backend-серверов.
 type HealthChecker struct {
-	interval time.Duration
-	timeout  time.Duration
-	backends []*models.Server
-	log      *logger.Logger
+	interval    time.Duration
+	timeout     time.Duration
+	jitter      time.Duration // Максимальная случайная задержка перед проверкой сервера
+	concurrency int           // Максимальное число одновременных проверок
+	backends    []*models.Server
+	log         *logger.Logger
+
+	probes   map[*models.Server]prober // Проверки по серверам
+	defaults config.Probe              // Общие настройки проверки для добавляемых серверов
+	client   *http.Client
+	tls      *tls.Config
+
+	healthyThreshold   int            // Успешных проверок подряд для возврата сервера
+	unhealthyThreshold int            // Неуспешных проверок подряд для исключения сервера
+	flap               config.Flap    // Настройки подавления "мигания" серверов
+	passive            config.Passive // Настройки пассивной проверки
+
+	states map[*models.Server]*serverState // Состояние проверок по серверам
+	mu     sync.Mutex                      // Защищает backends, probes и states
 }
 
 // NewHealthChecker создает новый экземпляр HealthChecker.
-// Принимает интервал проверки, таймаут и список серверов.
-func NewHealthChecker(interval time.Duration, timeout time.Duration, backends []*models.Server, log *logger.Logger) *HealthChecker {
+// Принимает настройки проверки из конфига, список серверов и настройки TLS
+// для backend-ов с https (nil — настройки по умолчанию).
+// Возвращает ошибку при некорректных настройках проверки.
+func NewHealthChecker(cfg config.HealthChecker, backends []*models.Server, tlsCfg *tls.Config, log *logger.Logger) (*HealthChecker, error) {
+	client := newClient(max(cfg.Concurrency, 1), tlsCfg)
+	probes := make(map[*models.Server]prober, len(backends))
+	for _, backend := range backends {
+		p, err := newProbe(cfg.Probe, backend.Probe, client, tlsCfg)
+		if err != nil {
+			return nil, fmt.Errorf("backend %s: %v", backend.URL, err)
+		}
+		probes[backend] = p
+	}
+
 	return &HealthChecker{
-		interval: interval,
-		timeout:  timeout,
-		backends: backends,
-		log:      log,
+		interval:    cfg.Interval,
+		timeout:     cfg.Timeout,
+		jitter:      cfg.Jitter,
+		concurrency: max(cfg.Concurrency, 1),
+		backends:    backends,
+		log:         log,
+
+		probes:   probes,
+		defaults: cfg.Probe,
+		client:   client,
+		tls:      tlsCfg,
+
+		healthyThreshold:   max(cfg.HealthyThreshold, 1),
+		unhealthyThreshold: max(cfg.UnhealthyThreshold, 1),
+		flap:               cfg.Flap,
+		passive:            cfg.Passive,
+
+		states: make(map[*models.Server]*serverState),
+	}, nil
+}
+
+// newClient создает HTTP-клиент проверок с общим переиспользуемым транспортом.
+// Таймаут задается контекстом каждой проверки.
+func newClient(concurrency int, tlsCfg *tls.Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.MaxIdleConns = concurrency * 2
+	transport.MaxIdleConnsPerHost = 2
+	transport.IdleConnTimeout = 90 * time.Second
+	if tlsCfg != nil {
+		transport.TLSClientConfig = tlsCfg.Clone()
 	}
+
+	return &http.Client{Transport: transport}
 }
 
 // Run запускает периодические проверки состояния серверов.
@@ -38,35 +104,120 @@ func (hc *HealthChecker) Run(ctx context.Context) {
 	for {
 		select {
 		case <-ticker.C:
-			for _, backend := range hc.backends {
+			hc.checkAll(ctx)
+			if ctx.Err() != nil {
+				hc.stop()
+				return
+			}
+		case <-ctx.Done():
+			hc.stop()
+			return
+		}
+	}
+}
+
+// stop освобождает ресурсы проверок после остановки.
+func (hc *HealthChecker) stop() {
+	hc.mu.Lock()
+	for _, p := range hc.probes {
+		p.close()
+	}
+	hc.mu.Unlock()
+	hc.log.Info("Healthchecker stopped")
+}
+
+// AddBackend добавляет сервер в проверки.
+// Возвращает ошибку при некорректных настройках проверки сервера.
+func (hc *HealthChecker) AddBackend(backend *models.Server) error {
+	p, err := newProbe(hc.defaults, backend.Probe, hc.client, hc.tls)
+	if err != nil {
+		return err
+	}
+
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	hc.backends = append(slices.Clone(hc.backends), backend)
+	hc.probes[backend] = p
+	return nil
+}
+
+// RemoveBackend исключает сервер из проверок и освобождает ресурсы его проверки.
+func (hc *HealthChecker) RemoveBackend(backend *models.Server) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	hc.backends = slices.DeleteFunc(slices.Clone(hc.backends), func(s *models.Server) bool { return s == backend })
+	if p, ok := hc.probes[backend]; ok {
+		p.close()
+		delete(hc.probes, backend)
+	}
+	delete(hc.states, backend)
+}
+
+// checkAll проверяет все серверы параллельно, не более concurrency
+// проверок одновременно. Перед проверкой каждого сервера выдерживается
+// случайная задержка до jitter, чтобы проверки не уходили одновременно.
+// Возвращает управление после завершения всех проверок раунда.
+func (hc *HealthChecker) checkAll(ctx context.Context) {
+	sem := make(chan struct{}, hc.concurrency)
+	var wg sync.WaitGroup
+
+	hc.mu.Lock()
+	backends := hc.backends
+	hc.mu.Unlock()
+
+	for _, backend := range backends {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if hc.jitter > 0 {
+				delay := time.NewTimer(rand.N(hc.jitter))
+				defer delay.Stop()
 				select {
+				case <-delay.C:
 				case <-ctx.Done():
-					hc.log.Info("Healthchecker stopped")
 					return
-				default:
-					hc.check(backend)
 				}
 			}
-		case <-ctx.Done():
-			hc.log.Info("Healthchecker stopped")
-			return
-		}
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+
+			hc.check(ctx, backend)
+		}()
 	}
+	wg.Wait()
 }
 
-func (hc *HealthChecker) check(backend *models.Server) {
-	client := http.Client{
-		Timeout: hc.interval,
+func (hc *HealthChecker) check(ctx context.Context, backend *models.Server) {
+	ctx, cancel := context.WithTimeout(ctx, hc.timeout)
+	defer cancel()
+
+	p, ok := hc.prober(backend)
+	if !ok {
+		return
 	}
-	resp, err := client.Get(backend.URL.String() + "/healthcheck")
-	if resp != nil {
-		defer resp.Body.Close()
+	err := p.probe(ctx, backend.URL)
+	if ctx.Err() == context.Canceled {
+		return
 	}
-	if err != nil || resp.StatusCode != http.StatusOK {
-		hc.log.Error("Healthcheck failed for backend: ", zap.String("backend", backend.URL.String()))
-		backend.SetAlive(false)
+	if err != nil {
+		hc.log.Debug("Healthcheck failed for backend: ", zap.String("backend", backend.URL.String()), zap.Error(err))
 	} else {
 		hc.log.Debug("Healthcheck passed for backend: ", zap.String("backend", backend.URL.String()))
-		backend.SetAlive(true)
 	}
+	hc.observe(backend, err)
+}
+
+// prober возвращает проверку сервера или false, если сервер удален из проверок.
+func (hc *HealthChecker) prober(backend *models.Server) (prober, bool) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	p, ok := hc.probes[backend]
+	return p, ok
 }
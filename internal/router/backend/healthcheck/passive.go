@@ -0,0 +1,80 @@
+package healthcheck
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/models"
+)
+
+// passiveState хранит результаты проксированных запросов к серверу.
+type passiveState struct {
+	failures  int       // Количество ошибок подряд
+	firstFail time.Time // Время первой ошибки в текущей серии
+	ejected   bool      // Сервер исключен пассивной проверкой
+	ejectedAt time.Time // Время исключения
+}
+
+// reset сбрасывает серию ошибок и отменяет возврат сервера по cooldown.
+func (ps *passiveState) reset() {
+	ps.failures = 0
+	ps.ejected = false
+}
+
+// ReportResult учитывает результат проксированного запроса к серверу.
+// После max_failures ошибок подряд в пределах window сервер исключается
+// из балансировки на время cooldown или до успешной активной проверки.
+func (hc *HealthChecker) ReportResult(backend *models.Server, failed bool) {
+	if !hc.passive.Enabled {
+		return
+	}
+
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	if _, ok := hc.probes[backend]; !ok {
+		return // Запрос завершился после удаления сервера
+	}
+	ps := &hc.state(backend).passive
+	if !failed {
+		ps.failures = 0
+		return
+	}
+
+	now := time.Now()
+	if ps.failures == 0 || now.Sub(ps.firstFail) > hc.passive.Window {
+		ps.failures = 0
+		ps.firstFail = now
+	}
+	ps.failures++
+
+	if ps.ejected || ps.failures < hc.passive.MaxFailures || !backend.IsAlive() {
+		return
+	}
+
+	ps.ejected = true
+	ps.ejectedAt = now
+	hc.transition(backend, hc.state(backend), false,
+		fmt.Sprintf("passive healthcheck: %d consecutive failures", ps.failures))
+
+	time.AfterFunc(hc.passive.Cooldown, func() {
+		hc.restorePassive(backend, now)
+	})
+}
+
+// restorePassive возвращает сервер в балансировку после cooldown,
+// если с момента исключения его состояние не менялось.
+func (hc *HealthChecker) restorePassive(backend *models.Server, ejectedAt time.Time) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	if _, ok := hc.probes[backend]; !ok {
+		return // Сервер удален во время cooldown
+	}
+	st := hc.state(backend)
+	if !st.passive.ejected || !st.passive.ejectedAt.Equal(ejectedAt) {
+		return
+	}
+	st.passive.reset()
+	hc.transition(backend, st, true, "passive healthcheck cooldown expired")
+}
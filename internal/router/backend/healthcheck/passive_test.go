@@ -0,0 +1,68 @@
+package healthcheck
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/config"
+	"github.com/DblMOKRQ/cloud_test_task/internal/models"
+	logger "github.com/DblMOKRQ/cloud_test_task/pkg"
+	"go.uber.org/zap"
+)
+
+func newPassiveChecker(t *testing.T, servers ...*models.Server) *HealthChecker {
+	t.Helper()
+	hc, err := NewHealthChecker(config.HealthChecker{
+		Interval: time.Minute,
+		Timeout:  time.Second,
+		Passive:  config.Passive{Enabled: true, MaxFailures: 2, Window: time.Minute, Cooldown: time.Hour},
+	}, servers, nil, &logger.Logger{Logger: zap.NewNop()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hc
+}
+
+func newServer() *models.Server {
+	return &models.Server{URL: &url.URL{Scheme: "http", Host: "backend:80"}, Alive: true, Weight: 1}
+}
+
+// Запрос, завершившийся после удаления сервера, не создает его состояние заново.
+func TestReportResultAfterRemove(t *testing.T) {
+	server := newServer()
+	hc := newPassiveChecker(t, server)
+	hc.RemoveBackend(server)
+
+	hc.ReportResult(server, true)
+	hc.ReportResult(server, true)
+
+	if _, ok := hc.states[server]; ok {
+		t.Fatal("state re-created for a removed backend")
+	}
+	if !server.IsAlive() {
+		t.Fatal("removed backend ejected by passive healthcheck")
+	}
+}
+
+// Возврат по cooldown не меняет состояние сервера, удаленного после исключения.
+func TestRestorePassiveAfterRemove(t *testing.T) {
+	server := newServer()
+	hc := newPassiveChecker(t, server)
+	hc.ReportResult(server, true)
+	hc.ReportResult(server, true)
+	if server.IsAlive() {
+		t.Fatal("backend not ejected after max_failures")
+	}
+	ejectedAt := hc.states[server].passive.ejectedAt
+
+	hc.RemoveBackend(server)
+	hc.restorePassive(server, ejectedAt)
+
+	if _, ok := hc.states[server]; ok {
+		t.Fatal("state re-created for a removed backend")
+	}
+	if server.IsAlive() {
+		t.Fatal("removed backend restored after cooldown")
+	}
+}
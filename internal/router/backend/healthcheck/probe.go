@@ -0,0 +1,181 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/config"
+)
+
+// maxProbeBody ограничивает объем тела ответа, читаемого при проверке.
+const maxProbeBody = 64 << 10
+
+// prober выполняет проверку состояния сервера.
+type prober interface {
+	// probe возвращает ошибку, если сервер недоступен или не прошел проверку.
+	probe(ctx context.Context, target *url.URL) error
+	// close освобождает ресурсы проверки.
+	close()
+}
+
+// newProbe собирает проверку из общих настроек и переопределений backend-а.
+// Возвращает ошибку при неизвестном типе проверки или некорректном регулярном выражении.
+func newProbe(defaults config.Probe, override *config.Probe, client *http.Client, tlsCfg *tls.Config) (prober, error) {
+	merged := mergeProbe(defaults, override)
+	switch merged.Type {
+	case "", "http":
+		return newHTTPProbe(merged, client)
+	case "tcp":
+		return &tcpProbe{}, nil
+	case "grpc":
+		return &grpcProbe{service: merged.GRPCService, tls: tlsCfg}, nil
+	default:
+		return nil, fmt.Errorf("unknown healthcheck type %q", merged.Type)
+	}
+}
+
+// mergeProbe накладывает заданные поля переопределения на общие настройки.
+func mergeProbe(defaults config.Probe, override *config.Probe) config.Probe {
+	merged := defaults
+	if override == nil {
+		return merged
+	}
+	if override.Type != "" {
+		merged.Type = override.Type
+	}
+	if override.Path != "" {
+		merged.Path = override.Path
+	}
+	if override.Method != "" {
+		merged.Method = override.Method
+	}
+	if len(override.ExpectedStatus) > 0 {
+		merged.ExpectedStatus = override.ExpectedStatus
+	}
+	if override.BodyMatch != "" {
+		merged.BodyMatch = override.BodyMatch
+	}
+	if override.GRPCService != "" {
+		merged.GRPCService = override.GRPCService
+	}
+	if len(override.Headers) > 0 {
+		headers := make(map[string]string, len(defaults.Headers)+len(override.Headers))
+		for k, v := range defaults.Headers {
+			headers[k] = v
+		}
+		for k, v := range override.Headers {
+			headers[k] = v
+		}
+		merged.Headers = headers
+	}
+	return merged
+}
+
+// httpProbe описывает HTTP-проверку состояния сервера.
+type httpProbe struct {
+	client   *http.Client
+	path     string
+	method   string
+	headers  http.Header
+	statuses map[int]struct{}
+	body     *regexp.Regexp
+}
+
+func newHTTPProbe(cfg config.Probe, client *http.Client) (*httpProbe, error) {
+	p := &httpProbe{
+		client:   client,
+		path:     cfg.Path,
+		method:   strings.ToUpper(cfg.Method),
+		headers:  make(http.Header, len(cfg.Headers)),
+		statuses: make(map[int]struct{}, len(cfg.ExpectedStatus)),
+	}
+	if p.path == "" {
+		p.path = "/healthcheck"
+	}
+	if p.method == "" {
+		p.method = http.MethodGet
+	}
+	for k, v := range cfg.Headers {
+		p.headers.Set(k, v)
+	}
+	for _, code := range cfg.ExpectedStatus {
+		p.statuses[code] = struct{}{}
+	}
+	if len(p.statuses) == 0 {
+		p.statuses[http.StatusOK] = struct{}{}
+	}
+	if cfg.BodyMatch != "" {
+		re, err := regexp.Compile(cfg.BodyMatch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile body_match: %v", err)
+		}
+		p.body = re
+	}
+	return p, nil
+}
+
+func (p *httpProbe) probe(ctx context.Context, target *url.URL) error {
+	req, err := p.request(ctx, target)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return p.verify(resp)
+}
+
+func (p *httpProbe) close() {}
+
+// request создает запрос проверки к указанному серверу.
+func (p *httpProbe) request(ctx context.Context, base *url.URL) (*http.Request, error) {
+	target := base.JoinPath(p.path)
+	req, err := http.NewRequestWithContext(ctx, p.method, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range p.headers {
+		req.Header[k] = v
+	}
+	if host := p.headers.Get("Host"); host != "" {
+		req.Host = host
+	}
+	return req, nil
+}
+
+// verify проверяет код ответа и, если задано, содержимое тела.
+func (p *httpProbe) verify(resp *http.Response) error {
+	if _, ok := p.statuses[resp.StatusCode]; !ok {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	if p.body == nil {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBody))
+	if err != nil {
+		return fmt.Errorf("failed to read body: %v", err)
+	}
+	if !p.body.Match(body) {
+		return fmt.Errorf("body does not match %q", p.body.String())
+	}
+	return nil
+}
+
+// hostPort возвращает адрес сервера, подставляя порт по умолчанию для схемы.
+func hostPort(target *url.URL) string {
+	if target.Port() != "" {
+		return target.Host
+	}
+	if target.Scheme == "https" {
+		return target.Host + ":443"
+	}
+	return target.Host + ":80"
+}
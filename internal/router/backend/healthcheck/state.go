@@ -0,0 +1,109 @@
+package healthcheck
+
+import (
+	"time"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/models"
+	"go.uber.org/zap"
+)
+
+// serverState хранит историю проверок сервера.
+type serverState struct {
+	successes   int         // Успешных активных проверок подряд
+	failures    int         // Неуспешных активных проверок подряд
+	transitions []time.Time // Время последних смен состояния в пределах окна flap
+	heldUntil   time.Time   // Сервер удерживается вне балансировки до этого момента
+
+	passive passiveState // Состояние пассивной проверки
+}
+
+// state возвращает состояние сервера, создавая его при первом обращении.
+// Вызывается под hc.mu.
+func (hc *HealthChecker) state(backend *models.Server) *serverState {
+	st, ok := hc.states[backend]
+	if !ok {
+		st = &serverState{}
+		hc.states[backend] = st
+	}
+	return st
+}
+
+// observe учитывает результат активной проверки сервера.
+// Сервер исключается после unhealthy_threshold неудач подряд
+// и возвращается после healthy_threshold успехов подряд.
+func (hc *HealthChecker) observe(backend *models.Server, err error) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	if _, ok := hc.probes[backend]; !ok {
+		return // Сервер удален во время проверки
+	}
+	st := hc.state(backend)
+	if err != nil {
+		st.successes = 0
+		st.failures++
+		// Активная проверка берет управление на себя: сервер, исключенный
+		// пассивной проверкой, больше не вернется по истечении cooldown.
+		st.passive.reset()
+		if st.failures >= hc.unhealthyThreshold {
+			hc.transition(backend, st, false, "healthcheck failed: "+err.Error())
+		}
+		return
+	}
+
+	st.failures = 0
+	st.successes++
+	if st.successes >= hc.healthyThreshold {
+		st.passive.reset()
+		hc.transition(backend, st, true, "healthcheck passed")
+	}
+}
+
+// transition переводит сервер в новое состояние и логирует причину.
+// Если сервер слишком часто меняет состояние, он удерживается вне
+// балансировки на время flap.hold. Вызывается под hc.mu.
+func (hc *HealthChecker) transition(backend *models.Server, st *serverState, alive bool, reason string) {
+	if backend.IsAlive() == alive {
+		return
+	}
+
+	now := time.Now()
+	if alive && now.Before(st.heldUntil) {
+		return
+	}
+
+	if hc.flap.Enabled {
+		cutoff := now.Add(-hc.flap.Window)
+		recent := st.transitions[:0]
+		for _, t := range st.transitions {
+			if t.After(cutoff) {
+				recent = append(recent, t)
+			}
+		}
+		st.transitions = append(recent, now)
+
+		if alive && len(st.transitions) >= hc.flap.MaxTransitions {
+			st.heldUntil = now.Add(hc.flap.Hold)
+			st.transitions = st.transitions[:0]
+			hc.log.Warn("Backend held out of rotation due to flapping",
+				zap.String("backend", backend.URL.String()),
+				zap.String("reason", reason),
+				zap.Duration("hold", hc.flap.Hold),
+			)
+			return
+		}
+	}
+
+	backend.SetAlive(alive)
+	if alive {
+		hc.log.Info("Backend marked healthy",
+			zap.String("backend", backend.URL.String()),
+			zap.String("reason", reason),
+		)
+	} else {
+		hc.log.Warn("Backend marked unhealthy",
+			zap.String("backend", backend.URL.String()),
+			zap.String("reason", reason),
+		)
+	}
+}
@@ -0,0 +1,22 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"net/url"
+)
+
+// tcpProbe проверяет только то, что порт сервера принимает соединения.
+type tcpProbe struct {
+	dialer net.Dialer
+}
+
+func (p *tcpProbe) probe(ctx context.Context, target *url.URL) error {
+	conn, err := p.dialer.DialContext(ctx, "tcp", hostPort(target))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (p *tcpProbe) close() {}
@@ -0,0 +1,262 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/config"
+	logger "github.com/DblMOKRQ/cloud_test_task/pkg"
+	"go.uber.org/zap"
+)
+
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Store хранит сертификаты, выбирает их по SNI и перечитывает
+// с диска при изменении файлов.
+type Store struct {
+	files []config.Certificate
+	log   *logger.Logger
+
+	mu      sync.RWMutex
+	certs   []*tls.Certificate
+	byName  map[string]*tls.Certificate
+	modTime []time.Time
+}
+
+// NewStore загружает сертификаты из файлов.
+// Возвращает ошибку, если хотя бы одну пару не удалось загрузить.
+func NewStore(files []config.Certificate, log *logger.Logger) (*Store, error) {
+	s := &Store{files: files, log: log}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// TLSConfig создает настройки TLS-сервера, использующие сертификаты из Store.
+func (s *Store) TLSConfig(cfg config.TLS) (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		MinVersion:     versions[cfg.MinVersion],
+		GetCertificate: s.GetCertificate,
+	}
+	for _, name := range cfg.CipherSuites {
+		id, err := cipherSuite(name)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.CipherSuites = append(tlsCfg.CipherSuites, id)
+	}
+	return tlsCfg, nil
+}
+
+// GetCertificate выбирает сертификат по имени из SNI: сначала точное
+// совпадение, затем wildcard-сертификат. Без подходящего сертификата
+// или SNI возвращается первый сертификат.
+func (s *Store) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	if cert, ok := s.byName[name]; ok {
+		return cert, nil
+	}
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		if cert, ok := s.byName["*"+name[i:]]; ok {
+			return cert, nil
+		}
+	}
+	return s.certs[0], nil
+}
+
+// Run периодически проверяет файлы сертификатов и перечитывает их при изменении.
+// При ошибке загрузки продолжают использоваться прежние сертификаты.
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !s.changed() {
+				continue
+			}
+			if err := s.load(); err != nil {
+				s.log.Error("Failed to reload TLS certificates", zap.Error(err))
+				continue
+			}
+			s.log.Info("TLS certificates reloaded")
+		}
+	}
+}
+
+// load читает все пары сертификатов и атомарно заменяет текущие.
+func (s *Store) load() error {
+	certs := make([]*tls.Certificate, 0, len(s.files))
+	byName := make(map[string]*tls.Certificate)
+	modTime := make([]time.Time, 0, len(s.files))
+
+	for _, f := range s.files {
+		mod, err := lastModified(f)
+		if err != nil {
+			return err
+		}
+		cert, err := tls.LoadX509KeyPair(f.CertFile, f.KeyFile)
+		if err != nil {
+			return fmt.Errorf("load certificate %s: %v", f.CertFile, err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return fmt.Errorf("parse certificate %s: %v", f.CertFile, err)
+		}
+		cert.Leaf = leaf
+
+		names := leaf.DNSNames
+		if len(names) == 0 && leaf.Subject.CommonName != "" {
+			names = []string{leaf.Subject.CommonName}
+		}
+		for _, name := range names {
+			name = strings.ToLower(name)
+			if _, ok := byName[name]; !ok {
+				byName[name] = &cert
+			}
+		}
+		certs = append(certs, &cert)
+		modTime = append(modTime, mod)
+	}
+
+	s.mu.Lock()
+	s.certs, s.byName, s.modTime = certs, byName, modTime
+	s.mu.Unlock()
+	return nil
+}
+
+// changed сообщает, изменились ли файлы сертификатов с последней загрузки.
+func (s *Store) changed() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i, f := range s.files {
+		mod, err := lastModified(f)
+		if err != nil {
+			s.log.Warn("Failed to stat TLS certificate", zap.String("cert_file", f.CertFile), zap.Error(err))
+			return false
+		}
+		if !mod.Equal(s.modTime[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// lastModified возвращает время последнего изменения пары файлов.
+func lastModified(f config.Certificate) (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{f.CertFile, f.KeyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func cipherSuite(name string) (uint16, error) {
+	for _, suite := range tls.CipherSuites() {
+		if suite.Name == name {
+			return suite.ID, nil
+		}
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		if suite.Name == name {
+			return 0, fmt.Errorf("cipher suite %s is insecure", name)
+		}
+	}
+	return 0, errors.New("unknown cipher suite " + name)
+}
+
+// ClientConfig создает настройки TLS для соединений с backend-ами:
+// бандл CA, клиентский сертификат для mTLS и имя сервера.
+// Возвращает nil, если настройки не заданы.
+func ClientConfig(cfg config.UpstreamTLS) (*tls.Config, error) {
+	if cfg == (config.UpstreamTLS{}) {
+		return nil, nil
+	}
+
+	tlsCfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.CAFile != "" {
+		roots, err := loadCAPool(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.RootCAs = roots
+	}
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate %s: %v", cfg.CertFile, err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
+
+// AdminConfig создает настройки TLS для слушателя admin API.
+// При заданном client_ca_file клиенты обязаны предъявить сертификат,
+// подписанный этим CA. Возвращает nil, если TLS не настроен.
+func AdminConfig(cfg config.AdminTLS) (*tls.Config, error) {
+	if cfg.CertFile == "" {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load certificate %s: %v", cfg.CertFile, err)
+	}
+	tlsCfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if cfg.ClientCAFile != "" {
+		clientCAs, err := loadCAPool(cfg.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.ClientCAs = clientCAs
+		tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsCfg, nil
+}
+
+// loadCAPool читает бандл CA в формате PEM.
+func loadCAPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read CA bundle: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,201 @@
+package forwarded
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type clientIPKey struct{}
+
+// Resolver определяет реальный IP клиента с учетом доверенных прокси
+// и формирует заголовки X-Forwarded-* и Forwarded (RFC 7239) для backend-ов.
+// Заголовки от недоверенных узлов игнорируются.
+type Resolver struct {
+	trusted []*net.IPNet
+}
+
+// NewResolver создает Resolver по списку доверенных сетей в нотации CIDR.
+// Одиночный IP-адрес трактуется как сеть из одного адреса.
+func NewResolver(cidrs []string) (*Resolver, error) {
+	res := &Resolver{trusted: make([]*net.IPNet, 0, len(cidrs))}
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", cidr)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			cidr = fmt.Sprintf("%s/%d", cidr, bits)
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %v", cidr, err)
+		}
+		res.trusted = append(res.trusted, network)
+	}
+	return res, nil
+}
+
+// Middleware определяет IP клиента и сохраняет его в контексте запроса.
+func (res *Resolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), clientIPKey{}, res.resolve(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ClientIP возвращает IP клиента, определенный Middleware.
+// Без Middleware возвращает адрес узла, установившего соединение.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return peer(r)
+}
+
+// SetHeaders выставляет заголовки X-Forwarded-Proto, X-Forwarded-Host
+// и Forwarded исходящего запроса и подготавливает X-Forwarded-For,
+// к которому reverse proxy добавит адрес узла. Заголовки недоверенного
+// узла удаляются.
+func (res *Resolver) SetHeaders(out *http.Request) {
+	p := peer(out)
+	trusted := res.isTrusted(p)
+	if !trusted {
+		out.Header.Del("X-Forwarded-For")
+		out.Header.Del("X-Forwarded-Proto")
+		out.Header.Del("X-Forwarded-Host")
+		out.Header.Del("Forwarded")
+	}
+
+	proto := "http"
+	if out.TLS != nil {
+		proto = "https"
+	}
+	if out.Header.Get("X-Forwarded-Proto") == "" {
+		out.Header.Set("X-Forwarded-Proto", proto)
+	}
+	if out.Header.Get("X-Forwarded-Host") == "" {
+		out.Header.Set("X-Forwarded-Host", out.Host)
+	}
+
+	elems := out.Header.Values("Forwarded")
+	if len(elems) == 0 {
+		for _, ip := range forwardedFor(out.Header) {
+			elems = append(elems, "for="+node(ip))
+		}
+	}
+	elems = append(elems, fmt.Sprintf("for=%s;host=%s;proto=%s", node(p), quote(out.Host), proto))
+	out.Header.Set("Forwarded", strings.Join(elems, ", "))
+}
+
+// resolve возвращает IP клиента: если соединение установил доверенный
+// прокси, цепочка X-Forwarded-For (или Forwarded) просматривается справа
+// налево до первого недоверенного адреса.
+func (res *Resolver) resolve(r *http.Request) string {
+	p := peer(r)
+	if !res.isTrusted(p) {
+		return p
+	}
+
+	chain := forwardedFor(r.Header)
+	if len(chain) == 0 {
+		chain = forwardedElems(r.Header)
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		if !res.isTrusted(chain[i]) {
+			return chain[i]
+		}
+	}
+	if len(chain) > 0 {
+		return chain[0]
+	}
+	return p
+}
+
+func (res *Resolver) isTrusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range res.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// peer возвращает IP узла, установившего соединение.
+func peer(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// forwardedFor возвращает адреса из всех заголовков X-Forwarded-For.
+func forwardedFor(h http.Header) []string {
+	var chain []string
+	for _, v := range h.Values("X-Forwarded-For") {
+		for _, ip := range strings.Split(v, ",") {
+			if ip = strings.TrimSpace(ip); ip != "" {
+				chain = append(chain, ip)
+			}
+		}
+	}
+	return chain
+}
+
+// forwardedElems возвращает адреса из параметров for= заголовков Forwarded.
+func forwardedElems(h http.Header) []string {
+	var chain []string
+	for _, v := range h.Values("Forwarded") {
+		for _, elem := range strings.Split(v, ",") {
+			for _, pair := range strings.Split(elem, ";") {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok || !strings.EqualFold(key, "for") {
+					continue
+				}
+				value = strings.Trim(value, `"`)
+				if host, _, err := net.SplitHostPort(value); err == nil {
+					value = host
+				}
+				chain = append(chain, strings.Trim(value, "[]"))
+			}
+		}
+	}
+	return chain
+}
+
+// node форматирует адрес для параметра for= заголовка Forwarded.
+func node(ip string) string {
+	if strings.Contains(ip, ":") {
+		return `"[` + ip + `]"`
+	}
+	return ip
+}
+
+// quote заключает значение в кавычки, если оно не является token (RFC 7230).
+func quote(v string) string {
+	for _, c := range v {
+		if !isTokenChar(c) {
+			return `"` + strings.ReplaceAll(v, `"`, `\"`) + `"`
+		}
+	}
+	return v
+}
+
+func isTokenChar(c rune) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	}
+	return strings.ContainsRune("!#$%&'*+-.^_`|~", c)
+}
@@ -0,0 +1,180 @@
+package router
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/ratelimiter"
+	"github.com/DblMOKRQ/cloud_test_task/internal/router/errs"
+	"go.uber.org/zap"
+)
+
+// limitInfo описывает лимит клиента в ответах admin API.
+type limitInfo struct {
+	Client     string     `json:"client"`
+	Rate       int        `json:"rate"`
+	Burst      int        `json:"burst"`
+	Override   bool       `json:"override"` // false — действуют лимиты по умолчанию
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Remaining  *int       `json:"remaining,omitempty"` // Оставшиеся токены в Redis
+	ResetAfter string     `json:"reset_after,omitempty"`
+}
+
+func newLimitInfo(client string, o ratelimiter.Override) limitInfo {
+	info := limitInfo{Client: client, Rate: o.Rate, Burst: o.Burst, Override: true}
+	if !o.ExpiresAt.IsZero() {
+		expiresAt := o.ExpiresAt.UTC()
+		info.ExpiresAt = &expiresAt
+	}
+	return info
+}
+
+func newStatusInfo(client string, status ratelimiter.Status) limitInfo {
+	info := limitInfo{Client: client, Rate: status.Rate, Burst: status.Burst}
+	if status.Override {
+		info = newLimitInfo(client, ratelimiter.Override{Rate: status.Rate, Burst: status.Burst, ExpiresAt: status.ExpiresAt})
+	}
+	info.Remaining = &status.Remaining
+	info.ResetAfter = status.ResetAfter.String()
+	return info
+}
+
+// HandleLimits обрабатывает запросы к индивидуальным лимитам клиентов:
+// GET — список лимитов или состояние одного клиента (?client=),
+// PUT — установка лимита, DELETE — возврат клиента к лимитам по умолчанию.
+func (rt *Router) HandleLimits(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if client := r.URL.Query().Get("client"); client != "" {
+			rt.getLimit(w, r, client)
+			return
+		}
+		rt.listLimits(w, r)
+	case http.MethodPut:
+		rt.putLimit(w, r)
+	case http.MethodDelete:
+		client, ok := parseClient(w, r.URL.Query().Get("client"))
+		if !ok {
+			return
+		}
+		before, deleted, err := rt.RL.DeleteUserLimit(r.Context(), client)
+		if err != nil {
+			rt.log.Error("Failed to delete rate limit", zap.String("client", client), zap.Error(err))
+			errs.JSONError(w, errs.ErrorResponse{Error: "Failed to delete rate limit"}, http.StatusInternalServerError)
+			return
+		}
+		if !deleted {
+			errs.JSONError(w, errs.ErrorResponse{Error: "limit override not found"}, http.StatusNotFound)
+			return
+		}
+		rt.audit(r, "rate_limit.delete", client, newLimitInfo(client, before), nil)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		errs.JSONError(w, errs.ErrorResponse{Error: "Only GET, PUT and DELETE methods are allowed"}, http.StatusMethodNotAllowed)
+	}
+}
+
+func (rt *Router) getLimit(w http.ResponseWriter, r *http.Request, client string) {
+	client, ok := parseClient(w, client)
+	if !ok {
+		return
+	}
+	status, err := rt.RL.Status(r.Context(), client)
+	if err != nil {
+		rt.log.Error("Failed to get rate limit status", zap.String("client", client), zap.Error(err))
+		errs.JSONError(w, errs.ErrorResponse{Error: "Failed to get rate limit status"}, http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, newStatusInfo(client, status), http.StatusOK)
+}
+
+func (rt *Router) listLimits(w http.ResponseWriter, r *http.Request) {
+	overrides := rt.RL.UserLimits()
+	clients := make([]string, 0, len(overrides))
+	for client := range overrides {
+		clients = append(clients, client)
+	}
+	sort.Strings(clients)
+
+	limits := make([]limitInfo, 0, len(clients))
+	for _, client := range clients {
+		status, err := rt.RL.Status(r.Context(), client)
+		if err != nil {
+			rt.log.Error("Failed to get rate limit status", zap.String("client", client), zap.Error(err))
+			errs.JSONError(w, errs.ErrorResponse{Error: "Failed to get rate limit status"}, http.StatusInternalServerError)
+			return
+		}
+		if !status.Override {
+			continue // Лимит истек после получения списка
+		}
+		limits = append(limits, newStatusInfo(client, status))
+	}
+	writeJSON(w, limits, http.StatusOK)
+}
+
+func (rt *Router) putLimit(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Client string `json:"client"`
+		Rate   int    `json:"rate"`
+		Burst  int    `json:"burst"`
+		TTL    string `json:"ttl"` // Срок действия, например "30m"; пусто — бессрочно
+	}
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		rt.log.Error("Failed to decode request", zap.Error(err))
+		errs.JSONError(w, errs.ErrorResponse{Error: "Invalid request format"}, http.StatusBadRequest)
+		return
+	}
+
+	client, ok := parseClient(w, request.Client)
+	if !ok {
+		return
+	}
+	if request.Rate <= 0 || request.Burst <= 0 {
+		errs.JSONError(w, errs.ErrorResponse{Error: "rate and burst must be positive integers"}, http.StatusBadRequest)
+		return
+	}
+	var ttl time.Duration
+	if request.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(request.TTL); err != nil || ttl <= 0 {
+			errs.JSONError(w, errs.ErrorResponse{Error: "ttl must be a positive duration"}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	before, existed := rt.RL.UserLimit(client)
+	if err := rt.RL.SetUserLimit(r.Context(), client, request.Rate, request.Burst, ttl); err != nil {
+		rt.log.Error("Failed to update rate limit", zap.String("client", client), zap.Error(err))
+		errs.JSONError(w, errs.ErrorResponse{Error: "Failed to update rate limit"}, http.StatusInternalServerError)
+		return
+	}
+	after, _ := rt.RL.UserLimit(client)
+
+	var old any
+	if existed {
+		old = newLimitInfo(client, before)
+	}
+	info := newLimitInfo(client, after)
+	rt.audit(r, "rate_limit.set", client, old, info)
+	writeJSON(w, info, http.StatusOK)
+}
+
+// parseClient проверяет, что идентификатор клиента — IP-адрес,
+// и приводит его к виду, в котором он используется ограничителем запросов.
+// При ошибке отправляет 400 и возвращает false.
+func parseClient(w http.ResponseWriter, client string) (string, bool) {
+	if client == "" {
+		errs.JSONError(w, errs.ErrorResponse{Error: "client is required"}, http.StatusBadRequest)
+		return "", false
+	}
+	ip := net.ParseIP(client)
+	if ip == nil {
+		errs.JSONError(w, errs.ErrorResponse{Error: "client must be an IP address"}, http.StatusBadRequest)
+		return "", false
+	}
+	return ip.String(), true
+}
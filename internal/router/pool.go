@@ -0,0 +1,237 @@
+package router
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/config"
+	"github.com/DblMOKRQ/cloud_test_task/internal/models"
+	lb "github.com/DblMOKRQ/cloud_test_task/internal/router/backend/balancer"
+	"github.com/DblMOKRQ/cloud_test_task/internal/router/backend/circuitbreaker"
+	"github.com/DblMOKRQ/cloud_test_task/internal/router/backend/healthcheck"
+	"github.com/DblMOKRQ/cloud_test_task/internal/router/certs"
+	"github.com/DblMOKRQ/cloud_test_task/internal/router/forwarded"
+	"github.com/DblMOKRQ/cloud_test_task/internal/router/proxy"
+	logger "github.com/DblMOKRQ/cloud_test_task/pkg"
+	"go.uber.org/zap"
+)
+
+// pool — именованная группа backend-серверов со своим балансировщиком,
+// проверкой состояния и reverse proxy.
+type pool struct {
+	name    string
+	cfg     config.Pool           // Настройки, по которым создан пул
+	cb      config.CircuitBreaker // Настройки breaker-а для добавляемых серверов
+	mu      sync.RWMutex          // Защищает servers при изменении через admin API
+	servers []*models.Server
+	bal     balancer
+	hc      *healthcheck.HealthChecker
+	proxies *proxy.Registry
+	log     *logger.Logger
+	cancel  context.CancelFunc // Останавливает проверку состояния пула
+}
+
+var (
+	errBackendExists   = errors.New("backend already exists")
+	errBackendNotFound = errors.New("backend not found")
+)
+
+// newPool создает пул backend-ов по настройкам из конфига.
+// Пул с настройками upstream TLS или протоколом, отличным от auto,
+// получает собственный транспорт.
+// Возвращает ошибку, если не удалось создать серверы, балансировщик или healthchecker.
+func newPool(name string, cfg config.Pool, cb config.CircuitBreaker, transport *http.Transport, fwd *forwarded.Resolver, streams *proxy.Streams, log *logger.Logger) (*pool, error) {
+	log = log.With(zap.String("pool", name))
+
+	tlsCfg, err := certs.ClientConfig(cfg.UpstreamTLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsCfg != nil {
+		transport = transport.Clone()
+		transport.TLSClientConfig = tlsCfg.Clone() // Транспорты не должны делить один *tls.Config
+		if tlsCfg.InsecureSkipVerify {
+			log.Warn("Upstream TLS certificate verification is disabled")
+		}
+	}
+	var upstream http.RoundTripper = transport
+	switch cfg.Protocol {
+	case config.ProtocolHTTP1:
+		transport = transport.Clone()
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		// Без явного ALPN backend с поддержкой h2 все равно согласует HTTP/2
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.NextProtos = []string{"http/1.1"}
+		upstream = transport
+	case config.ProtocolH2C:
+		upstream = proxy.NewH2CTransport(transport)
+	}
+
+	servers, err := models.NewServers(cfg.Backends)
+	if err != nil {
+		return nil, err
+	}
+	if cb.Enabled {
+		circuitbreaker.Attach(servers, cb, log)
+	}
+
+	bal, err := lb.GetAlgorithm(cfg.Balancer, servers)
+	if err != nil {
+		return nil, err
+	}
+
+	hc, err := healthcheck.NewHealthChecker(cfg.HealthChecker, servers, tlsCfg, log)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pool{
+		name:    name,
+		cfg:     cfg,
+		cb:      cb,
+		servers: servers,
+		bal:     bal,
+		hc:      hc,
+		proxies: proxy.NewRegistry(upstream, fwd, streams, log, hc.ReportResult),
+		log:     log,
+	}, nil
+}
+
+// start запускает проверку состояния пула до отмены ctx или вызова stop.
+func (p *pool) start(ctx context.Context, wg *sync.WaitGroup) {
+	ctx, p.cancel = context.WithCancel(ctx)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.hc.Run(ctx)
+	}()
+}
+
+// stop останавливает проверку состояния пула, выведенного из конфигурации.
+func (p *pool) stop() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+}
+
+// inherit переносит состояние серверов с теми же URL из пула,
+// который заменяется при перезагрузке конфигурации.
+func (p *pool) inherit(old *pool) {
+	prev := make(map[string]*models.Server)
+	for _, server := range old.list() {
+		prev[server.URL.String()] = server
+	}
+	for _, server := range p.list() {
+		if s, ok := prev[server.URL.String()]; ok {
+			server.SetAlive(s.IsAlive())
+			server.SetDraining(s.IsDraining())
+		}
+	}
+}
+
+// list возвращает текущий список серверов пула.
+// Список не изменяется: при добавлении и удалении серверов создается новый.
+func (p *pool) list() []*models.Server {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.servers
+}
+
+// hasAlternative сообщает, есть ли в пуле другой сервер, доступный для запроса.
+// Без него неудачную попытку повторять некуда.
+func (p *pool) hasAlternative(r *http.Request, backend *models.Server) bool {
+	for _, server := range p.list() {
+		if server != backend && server.Available(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// find возвращает сервер пула по URL. Вызывается под p.mu.
+func (p *pool) find(rawURL string) *models.Server {
+	for _, server := range p.servers {
+		if server.URL.String() == rawURL {
+			return server
+		}
+	}
+	return nil
+}
+
+// addServer добавляет сервер в пул во время работы.
+// Сервер сразу попадает в балансировку и в проверки состояния.
+// Возвращает errBackendExists, если сервер с таким URL уже есть в пуле.
+func (p *pool) addServer(backend config.Backend) (*models.Server, error) {
+	created, err := models.NewServers([]config.Backend{backend})
+	if err != nil {
+		return nil, err
+	}
+	server := created[0]
+	if p.cfg.Protocol == config.ProtocolH2C && server.URL.Scheme == "https" {
+		return nil, errors.New("upstream_protocol h2c requires http backends")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.find(server.URL.String()) != nil {
+		return nil, errBackendExists
+	}
+	if p.cb.Enabled {
+		circuitbreaker.Attach(created, p.cb, p.log)
+	}
+	if err := p.hc.AddBackend(server); err != nil {
+		return nil, err
+	}
+
+	p.servers = append(slices.Clone(p.servers), server)
+	p.bal.SetServers(p.servers)
+	p.log.Info("Backend added", zap.String("backend", server.URL.String()))
+	return server, nil
+}
+
+// removeServer исключает сервер из пула во время работы.
+// Обрабатываемые сервером запросы завершаются, новые на него не попадают.
+// Возвращает удаленный сервер или errBackendNotFound, если сервера нет в пуле.
+func (p *pool) removeServer(rawURL string) (*models.Server, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	server := p.find(rawURL)
+	if server == nil {
+		return nil, errBackendNotFound
+	}
+
+	p.servers = slices.DeleteFunc(slices.Clone(p.servers), func(s *models.Server) bool { return s == server })
+	p.bal.SetServers(p.servers)
+	p.hc.RemoveBackend(server)
+	p.proxies.Remove(server)
+	p.log.Info("Backend removed", zap.String("backend", rawURL), zap.Int64("in_flight", server.InFlight()))
+	return server, nil
+}
+
+// drain выводит сервер из балансировки (draining = true) или возвращает его.
+// Возвращает сервер и его прежнее состояние или errBackendNotFound,
+// если сервера нет в пуле.
+func (p *pool) drain(rawURL string, draining bool) (*models.Server, bool, error) {
+	p.mu.RLock()
+	server := p.find(rawURL)
+	p.mu.RUnlock()
+	if server == nil {
+		return nil, false, errBackendNotFound
+	}
+
+	was := server.IsDraining()
+	server.SetDraining(draining)
+	if draining {
+		p.log.Info("Backend draining", zap.String("backend", rawURL), zap.Int64("in_flight", server.InFlight()))
+	} else {
+		p.log.Info("Backend resumed", zap.String("backend", rawURL))
+	}
+	return server, was, nil
+}
@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// errRetryStatus возвращается из ModifyResponse, когда ответ backend-а
+// нужно отбросить и повторить запрос на другом сервере.
+var errRetryStatus = errors.New("retryable upstream status")
+
+type attemptKey struct{}
+
+// Attempt описывает одну попытку проксирования запроса.
+type Attempt struct {
+	CanRetry    bool              // Неудачную попытку можно повторить на другом сервере
+	RetryStatus map[int]struct{}  // Коды ответа, при которых попытка считается неудачной
+	OnResponse  func()            // Вызывается при получении заголовков ответа
+	Rewrite     func(http.Header) // Изменяет заголовки ответа перед отправкой клиенту
+	Failed      bool              // Попытка не удалась, ответ клиенту не записан
+	Status      int               // Код ответа backend-а, 0 — ответ не получен
+	Err         error             // Ошибка проксирования
+	Responded   time.Time         // Время получения заголовков ответа
+	Stream      bool              // Ответ — WebSocket или поток
+	Discarded   *Response         // Ответ с повторяемым кодом, отброшенный ради повтора
+	Client      context.Context   // Контекст клиентского запроса, без таймаута попытки
+}
+
+// clientGone сообщает, что клиент ушел, не дождавшись ответа.
+// Истечение таймаута попытки уходом клиента не считается.
+func clientGone(r *http.Request, a *Attempt) bool {
+	if a != nil && a.Client != nil {
+		return a.Client.Err() != nil
+	}
+	return r.Context().Err() != nil
+}
+
+// maxDiscardedBody ограничивает размер тела отброшенного ответа,
+// которое сохраняется на случай, если повторить запрос не удастся.
+const maxDiscardedBody = 64 << 10
+
+// Response — ответ backend-а, отброшенный ради повтора. Отправляется клиенту,
+// если ни один другой сервер не смог обработать запрос.
+type Response struct {
+	status  int
+	header  http.Header
+	body    []byte
+	rewrite func(http.Header)
+}
+
+// discard сохраняет ответ и закрывает его тело.
+// Возвращает nil, если тело ответа слишком большое или не прочитано.
+func discard(resp *http.Response, rewrite func(http.Header)) *Response {
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDiscardedBody+1))
+	if err != nil || len(body) > maxDiscardedBody {
+		return nil
+	}
+	return &Response{status: resp.StatusCode, header: resp.Header.Clone(), body: body, rewrite: rewrite}
+}
+
+// Write отправляет сохраненный ответ клиенту.
+func (resp *Response) Write(w http.ResponseWriter) {
+	header := w.Header()
+	for k, vv := range resp.header {
+		for _, v := range vv {
+			header.Add(k, v)
+		}
+	}
+	if resp.rewrite != nil {
+		resp.rewrite(header)
+	}
+	header.Set("Content-Length", strconv.Itoa(len(resp.body)))
+	w.WriteHeader(resp.status)
+	w.Write(resp.body)
+}
+
+// WithAttempt привязывает попытку к запросу.
+func WithAttempt(r *http.Request, a *Attempt) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), attemptKey{}, a))
+}
+
+func attemptFrom(ctx context.Context) *Attempt {
+	a, _ := ctx.Value(attemptKey{}).(*Attempt)
+	return a
+}
@@ -1,22 +1,164 @@
 package proxy
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"sync"
+	"time"
 
+	"github.com/DblMOKRQ/cloud_test_task/internal/config"
+	"github.com/DblMOKRQ/cloud_test_task/internal/models"
 	"github.com/DblMOKRQ/cloud_test_task/internal/router/errs"
+	"github.com/DblMOKRQ/cloud_test_task/internal/router/forwarded"
 	logger "github.com/DblMOKRQ/cloud_test_task/pkg"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
 )
 
+// Registry хранит долгоживущие reverse proxy для backend-серверов.
+// Все proxy используют общий транспорт с пулом соединений.
+type Registry struct {
+	transport http.RoundTripper
+	fwd       *forwarded.Resolver
+	streams   *Streams
+	log       *logger.Logger
+	report    func(server *models.Server, failed bool)
+	proxies   sync.Map // *models.Server -> *httputil.ReverseProxy
+}
+
+// NewRegistry создает хранилище reverse proxy, использующих общий транспорт.
+// О результате каждого проксированного запроса сообщается в report.
+func NewRegistry(transport http.RoundTripper, fwd *forwarded.Resolver, streams *Streams, log *logger.Logger, report func(server *models.Server, failed bool)) *Registry {
+	return &Registry{
+		transport: transport,
+		fwd:       fwd,
+		streams:   streams,
+		log:       log,
+		report:    report,
+	}
+}
+
+// Get возвращает reverse proxy для сервера, создавая его при первом обращении.
+func (reg *Registry) Get(server *models.Server) *httputil.ReverseProxy {
+	if p, ok := reg.proxies.Load(server); ok {
+		return p.(*httputil.ReverseProxy)
+	}
+	p, _ := reg.proxies.LoadOrStore(server, Proxy(server.URL, reg.transport, reg.fwd, reg.streams, reg.log, func(failed bool) {
+		reg.report(server, failed)
+	}))
+	return p.(*httputil.ReverseProxy)
+}
+
+// Remove удаляет reverse proxy сервера, исключенного из конфигурации.
+func (reg *Registry) Remove(server *models.Server) {
+	reg.proxies.Delete(server)
+}
+
+// NewTransport создает транспорт для проксирования с настройками пула соединений и таймаутов.
+func NewTransport(cfg config.Transport) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   cfg.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.MaxIdleConns = cfg.MaxIdleConns
+	transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = cfg.IdleConnTimeout
+	transport.TLSHandshakeTimeout = cfg.TLSHandshakeTimeout
+	transport.ResponseHeaderTimeout = cfg.ResponseHeaderTimeout
+	return transport
+}
+
+// NewH2CTransport создает транспорт HTTP/2 без TLS (prior knowledge)
+// с подключением и таймаутами базового транспорта.
+func NewH2CTransport(base *http.Transport) *http2.Transport {
+	return &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return base.DialContext(ctx, network, addr)
+		},
+		IdleConnTimeout: base.IdleConnTimeout,
+		ReadIdleTimeout: 30 * time.Second,
+	}
+}
+
 // Proxy создает reverse proxy для указанного целевого URL.
-// Логирует ошибки проксирования запросов.
-func Proxy(target *url.URL, log *logger.Logger) *httputil.ReverseProxy {
+// Заголовки X-Forwarded-* и Forwarded выставляются через fwd,
+// WebSocket и потоковые ответы учитываются в streams.
+// Логирует ошибки проксирования запросов и сообщает о результате каждого
+// запроса в report: ошибкой считаются сбой соединения и ответ 5xx,
+// но не отмена запроса клиентом.
+// Если к запросу привязана повторяемая попытка (см. WithAttempt), то при
+// ошибке ответ клиенту не записывается, а попытка помечается неудачной;
+// отброшенный ответ с повторяемым кодом сохраняется в Attempt.Discarded.
+func Proxy(target *url.URL, transport http.RoundTripper, fwd *forwarded.Resolver, streams *Streams, log *logger.Logger, report func(failed bool)) *httputil.ReverseProxy {
 	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = transport
+	proxy.FlushInterval = streams.flush
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		fwd.SetHeaders(req)
+	}
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		report(resp.StatusCode >= http.StatusInternalServerError)
+
+		a := attemptFrom(resp.Request.Context())
+		if a != nil {
+			a.Status = resp.StatusCode
+			a.Responded = time.Now()
+			if a.OnResponse != nil {
+				a.OnResponse()
+			}
+			if _, retry := a.RetryStatus[resp.StatusCode]; retry && a.CanRetry {
+				a.Discarded = discard(resp, a.Rewrite)
+				return errRetryStatus
+			}
+			if a.Rewrite != nil {
+				a.Rewrite(resp.Header)
+			}
+		}
+		if isStream(resp) {
+			streams.track(resp)
+			if a != nil {
+				a.Stream = true
+			}
+		}
+		return nil
+	}
 	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-		log.Error("Proxying a request to the server " + target.String() + " failed: " + err.Error())
+		a := attemptFrom(r.Context())
+		if a != nil {
+			a.Err = err
+		}
+		// Ушедший клиент не говорит о состоянии backend-а
+		gone := clientGone(r, a)
+		if a != nil && a.CanRetry {
+			if !errors.Is(err, errRetryStatus) && !gone {
+				report(true)
+			}
+			log.Warn("Proxying attempt to the server failed",
+				zap.String("backend", target.String()),
+				zap.Error(err),
+			)
+			a.Failed = true
+			return
+		}
+
+		log.Error("Proxying a request to the server failed",
+			zap.String("backend", target.String()),
+			zap.String("client_ip", forwarded.ClientIP(r)),
+			zap.Error(err),
+		)
+		if !gone {
+			report(true)
+		}
 		errs.JSONError(w, errs.ErrorResponse{Error: "Service is unavailable"}, http.StatusBadGateway)
 	}
-	log.Info("Proxying a request to the server " + target.String())
 	return proxy
 }
@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/config"
+	"github.com/DblMOKRQ/cloud_test_task/internal/models"
+	"github.com/DblMOKRQ/cloud_test_task/internal/router/forwarded"
+	logger "github.com/DblMOKRQ/cloud_test_task/pkg"
+	"go.uber.org/zap"
+)
+
+var benchTransport = config.Transport{
+	MaxIdleConns:        100,
+	MaxIdleConnsPerHost: 100,
+	IdleConnTimeout:     90 * time.Second,
+	DialTimeout:         5 * time.Second,
+	TLSHandshakeTimeout: 10 * time.Second,
+}
+
+// benchBackend запускает backend и возвращает сервер балансировщика для него.
+func benchBackend(b *testing.B) *models.Server {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	b.Cleanup(backend.Close)
+
+	target, err := url.Parse(backend.URL)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return &models.Server{URL: target, Alive: true, Weight: 1}
+}
+
+func benchDeps(b *testing.B) (*forwarded.Resolver, *Streams, *logger.Logger) {
+	fwd, err := forwarded.NewResolver(nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return fwd, NewStreams(config.Streaming{}), &logger.Logger{Logger: zap.NewNop()}
+}
+
+func serveBench(b *testing.B, proxy func() http.Handler) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		proxy().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != http.StatusOK {
+			b.Fatalf("unexpected status %d", w.Code)
+		}
+	}
+}
+
+// BenchmarkProxyPerRequest — прежнее поведение: новый proxy на каждый запрос.
+func BenchmarkProxyPerRequest(b *testing.B) {
+	server := benchBackend(b)
+	fwd, streams, log := benchDeps(b)
+	transport := NewTransport(benchTransport)
+	b.Cleanup(transport.CloseIdleConnections)
+
+	serveBench(b, func() http.Handler {
+		return Proxy(server.URL, transport, fwd, streams, log, func(bool) {})
+	})
+}
+
+// BenchmarkRegistry — долгоживущий proxy из Registry на общем транспорте.
+func BenchmarkRegistry(b *testing.B) {
+	server := benchBackend(b)
+	fwd, streams, log := benchDeps(b)
+	transport := NewTransport(benchTransport)
+	b.Cleanup(transport.CloseIdleConnections)
+	reg := NewRegistry(transport, fwd, streams, log, func(*models.Server, bool) {})
+
+	serveBench(b, func() http.Handler {
+		return reg.Get(server)
+	})
+}
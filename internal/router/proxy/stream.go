@@ -0,0 +1,151 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/config"
+)
+
+// Streams отслеживает долгоживущие соединения — WebSocket и потоковые
+// ответы — и ограничивает их по времени простоя и общей длительности.
+type Streams struct {
+	flush       time.Duration // FlushInterval reverse proxy
+	idle        time.Duration // Время без данных до закрытия потока, 0 — без ограничения
+	maxDuration time.Duration // Максимальная длительность потока, 0 — без ограничения
+
+	mu     sync.Mutex
+	active map[*stream]struct{}
+}
+
+// NewStreams создает учет потоков по настройкам из конфига.
+func NewStreams(cfg config.Streaming) *Streams {
+	return &Streams{
+		flush:       cfg.FlushInterval,
+		idle:        cfg.IdleTimeout,
+		maxDuration: cfg.MaxDuration,
+		active:      make(map[*stream]struct{}),
+	}
+}
+
+// Active возвращает количество открытых потоков.
+func (s *Streams) Active() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.active)
+}
+
+// Shutdown ожидает завершения потоков не дольше timeout,
+// после чего закрывает оставшиеся.
+func (s *Streams) Shutdown(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for s.Active() > 0 && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	s.mu.Lock()
+	streams := make([]*stream, 0, len(s.active))
+	for st := range s.active {
+		streams = append(streams, st)
+	}
+	s.mu.Unlock()
+
+	for _, st := range streams {
+		st.Close()
+	}
+}
+
+// isStream сообщает, является ли ответ долгоживущим потоком:
+// переключением протокола, SSE или ответом без известной длины.
+func isStream(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		return true
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return true
+	}
+	return resp.ContentLength < 0 && resp.Request.Method != http.MethodHead
+}
+
+// track подменяет тело ответа обёрткой, которая учитывает поток
+// и закрывает его по таймаутам.
+func (s *Streams) track(resp *http.Response) {
+	st := &stream{body: resp.Body, owner: s}
+	if s.idle > 0 {
+		st.idleTimer = time.AfterFunc(s.idle, func() { st.Close() })
+	}
+	if s.maxDuration > 0 {
+		st.maxTimer = time.AfterFunc(s.maxDuration, func() { st.Close() })
+	}
+
+	s.mu.Lock()
+	s.active[st] = struct{}{}
+	s.mu.Unlock()
+
+	// Для переключения протокола reverse proxy требует тело, доступное для записи
+	if rwc, ok := resp.Body.(io.ReadWriteCloser); ok && resp.StatusCode == http.StatusSwitchingProtocols {
+		resp.Body = &upgradedStream{stream: st, w: rwc}
+		return
+	}
+	resp.Body = st
+}
+
+func (s *Streams) remove(st *stream) {
+	s.mu.Lock()
+	delete(s.active, st)
+	s.mu.Unlock()
+}
+
+// stream — тело ответа backend-а для потока.
+type stream struct {
+	body      io.ReadCloser
+	owner     *Streams
+	idleTimer *time.Timer
+	maxTimer  *time.Timer
+	once      sync.Once
+}
+
+func (st *stream) Read(p []byte) (int, error) {
+	n, err := st.body.Read(p)
+	st.touch()
+	return n, err
+}
+
+// Close закрывает соединение с backend-ом; безопасен для повторного
+// и конкурентного вызова.
+func (st *stream) Close() error {
+	var err error
+	st.once.Do(func() {
+		if st.idleTimer != nil {
+			st.idleTimer.Stop()
+		}
+		if st.maxTimer != nil {
+			st.maxTimer.Stop()
+		}
+		err = st.body.Close()
+		st.owner.remove(st)
+	})
+	return err
+}
+
+// touch откладывает закрытие по простою.
+func (st *stream) touch() {
+	if st.idleTimer != nil {
+		st.idleTimer.Reset(st.owner.idle)
+	}
+}
+
+// upgradedStream — соединение backend-а после переключения протокола.
+type upgradedStream struct {
+	*stream
+	w io.Writer
+}
+
+func (u *upgradedStream) Write(p []byte) (int, error) {
+	n, err := u.w.Write(p)
+	u.touch()
+	return n, err
+}
@@ -0,0 +1,206 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/config"
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// reloadDebounce — задержка перед перезагрузкой, чтобы серия изменений
+// файла конфигурации применялась один раз.
+const reloadDebounce = 500 * time.Millisecond
+
+// newState создает пулы и маршруты по конфигурации. Пулы с неизменными
+// настройками переиспользуются из old, у пересозданных пулов сохраняется
+// состояние серверов с теми же URL.
+func (rt *Router) newState(cfg *config.Config, old *state) (*state, error) {
+	poolCfgs := cfg.AllPools()
+	pools := make(map[string]*pool, len(poolCfgs))
+	for name, poolCfg := range poolCfgs {
+		var prev *pool
+		if old != nil {
+			prev = old.pools[name]
+		}
+		if prev != nil && reflect.DeepEqual(prev.cfg, poolCfg) && reflect.DeepEqual(old.cfg.CircuitBreaker, cfg.CircuitBreaker) {
+			pools[name] = prev
+			continue
+		}
+		p, err := newPool(name, poolCfg, cfg.CircuitBreaker, rt.transport, rt.fwd, rt.streams, rt.log)
+		if err != nil {
+			return nil, fmt.Errorf("pool %s: %v", name, err)
+		}
+		if prev != nil {
+			p.inherit(prev)
+		}
+		pools[name] = p
+	}
+
+	routes := make([]*route, 0, len(cfg.Routes))
+	for i, routeCfg := range cfg.Routes {
+		r, err := newRoute(routeCfg, pools[routeCfg.Pool])
+		if err != nil {
+			return nil, fmt.Errorf("route %d: %v", i, err)
+		}
+		routes = append(routes, r)
+	}
+
+	return &state{
+		cfg:      cfg,
+		pools:    pools,
+		routes:   routes,
+		fallback: fallbackRoute(pools),
+		retry:    newRetryPolicy(cfg.Retry),
+	}, nil
+}
+
+// watchReload перезагружает конфигурацию по SIGHUP и, если включено,
+// при изменении файла конфигурации. Работает до отмены контекста.
+func (rt *Router) watchReload(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var changes <-chan struct{}
+	if rt.cfg.Reload.Watch {
+		var err error
+		if changes, err = rt.watchFile(ctx, config.Path()); err != nil {
+			rt.log.Error("Failed to watch configuration file", zap.Error(err))
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			rt.reload(ctx, "signal")
+		case <-changes:
+			rt.reload(ctx, "file change")
+		}
+	}
+}
+
+// reload перечитывает конфигурацию и атомарно заменяет пулы, маршруты,
+// политику повторов и лимиты запросов. При ошибке продолжает
+// действовать прежняя конфигурация.
+func (rt *Router) reload(ctx context.Context, reason string) {
+	path := config.Path()
+	cfg, err := config.Load(path)
+	if err != nil {
+		rt.log.Error("Failed to reload configuration, keeping the current one", zap.String("path", path), zap.Error(err))
+		return
+	}
+
+	old := rt.state.Load()
+	st, err := rt.newState(cfg, old)
+	if err != nil {
+		rt.log.Error("Failed to apply configuration, keeping the current one", zap.String("path", path), zap.Error(err))
+		return
+	}
+	rt.state.Store(st)
+	rt.RL.SetDefaultLimit(cfg.Rate_limiting.Rate_per_second, cfg.Rate_limiting.Capacity)
+
+	for name, p := range st.pools {
+		if old.pools[name] != p {
+			p.start(ctx, &rt.shutdownWg)
+		}
+	}
+	for name, p := range old.pools {
+		if st.pools[name] != p {
+			p.stop()
+		}
+	}
+
+	if sections := restartRequired(rt.cfg, cfg); len(sections) > 0 {
+		rt.log.Warn("Configuration changes require a restart", zap.Strings("sections", sections))
+	}
+	rt.log.Info("Configuration reloaded", zap.String("reason", reason), zap.Int("pools", len(st.pools)), zap.Int("routes", len(st.routes)))
+}
+
+// restartRequired возвращает разделы конфигурации, изменения которых
+// применяются только после перезапуска.
+func restartRequired(running, cfg *config.Config) []string {
+	sections := []struct {
+		name            string
+		running, loaded any
+	}{
+		{"host", running.Host, cfg.Host},
+		{"port", running.Port, cfg.Port},
+		{"storage", running.Storage, cfg.Storage},
+		{"trusted_proxies", running.TrustedProxies, cfg.TrustedProxies},
+		{"tls", running.TLS, cfg.TLS},
+		{"http2", running.HTTP2, cfg.HTTP2},
+		{"transport", running.Transport, cfg.Transport},
+		{"streaming", running.Streaming, cfg.Streaming},
+		{"reload", running.Reload, cfg.Reload},
+		{"admin", running.Admin, cfg.Admin},
+	}
+	var changed []string
+	for _, s := range sections {
+		if !reflect.DeepEqual(s.running, s.loaded) {
+			changed = append(changed, s.name)
+		}
+	}
+	return changed
+}
+
+// watchFile сообщает об изменениях файла конфигурации. Отслеживается
+// каталог файла, так как редакторы и Kubernetes заменяют файл целиком
+// (в Kubernetes меняется символическая ссылка ..data).
+func (rt *Router) watchFile(ctx context.Context, path string) (<-chan struct{}, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	dir, name := filepath.Split(filepath.Clean(path))
+	if dir == "" {
+		dir = "."
+	}
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	changes := make(chan struct{}, 1)
+	rt.shutdownWg.Add(1)
+	go func() {
+		defer rt.shutdownWg.Done()
+		defer watcher.Close()
+
+		debounce := time.NewTimer(reloadDebounce)
+		debounce.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				debounce.Stop()
+				return
+			case event := <-watcher.Events:
+				base := filepath.Base(event.Name)
+				if base != name && !strings.HasPrefix(base, "..") {
+					continue
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0 {
+					debounce.Reset(reloadDebounce)
+				}
+			case err := <-watcher.Errors:
+				rt.log.Warn("Configuration watcher error", zap.Error(err))
+			case <-debounce.C:
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return changes, nil
+}
@@ -0,0 +1,135 @@
+package router
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/config"
+)
+
+// maxRetryBody ограничивает размер тела запроса, которое буферизуется для повторов.
+const maxRetryBody = 1 << 20
+
+// retryPolicy определяет, когда и сколько раз запрос повторяется на другом сервере.
+type retryPolicy struct {
+	attempts int                 // Всего попыток, включая первую
+	perTry   time.Duration       // Таймаут попытки до получения заголовков ответа
+	statuses map[int]struct{}    // Коды ответа, при которых запрос повторяется
+	methods  map[string]struct{} // Методы, которые разрешено повторять
+	budget   *retryBudget        // Общий для всех запросов лимит повторов
+}
+
+func newRetryPolicy(cfg config.Retry) *retryPolicy {
+	p := &retryPolicy{
+		attempts: max(cfg.Attempts, 1),
+		perTry:   cfg.PerTryTimeout,
+		statuses: make(map[int]struct{}, len(cfg.OnStatus)),
+		methods:  make(map[string]struct{}, len(cfg.Methods)),
+		budget:   newRetryBudget(cfg.Budget),
+	}
+	for _, code := range cfg.OnStatus {
+		p.statuses[code] = struct{}{}
+	}
+	for _, m := range cfg.Methods {
+		p.methods[strings.ToUpper(m)] = struct{}{}
+	}
+	return p
+}
+
+// prepare возвращает число попыток для запроса. Тело запроса
+// буферизуется, чтобы его можно было отправить повторно; если тело
+// слишком большое или его длина неизвестна, запрос не повторяется.
+func (p *retryPolicy) prepare(r *http.Request) (int, []byte, error) {
+	if p.attempts == 1 {
+		return 1, nil, nil
+	}
+	if _, ok := p.methods[r.Method]; !ok {
+		return 1, nil, nil
+	}
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return p.attempts, nil, nil
+	}
+	if r.ContentLength < 0 || r.ContentLength > maxRetryBody {
+		return 1, nil, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return 0, nil, err
+	}
+	return p.attempts, body, nil
+}
+
+// budgetWindow — за сколько секунд минимальной скорости повторов
+// может накопиться запас токенов бюджета.
+const budgetWindow = 10
+
+// retryBudget — token bucket повторов, общий для всех запросов.
+// Каждый запрос добавляет ratio токенов, каждый повтор забирает один,
+// кроме того токены пополняются со скоростью minPerSecond. Так повторов
+// в среднем не больше ratio от запросов плюс minPerSecond в секунду.
+type retryBudget struct {
+	mu        sync.Mutex
+	ratio     float64
+	perSecond float64
+	capacity  float64
+	tokens    float64
+	updated   time.Time
+}
+
+func newRetryBudget(cfg config.RetryBudget) *retryBudget {
+	capacity := max(float64(cfg.MinPerSecond*budgetWindow), 1)
+	return &retryBudget{
+		ratio:     cfg.Ratio,
+		perSecond: float64(cfg.MinPerSecond),
+		capacity:  capacity,
+		tokens:    capacity,
+		updated:   time.Now(),
+	}
+}
+
+// deposit учитывает запрос и пополняет бюджет.
+func (b *retryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(b.ratio)
+}
+
+// available сообщает, хватает ли бюджета на повтор, не расходуя его.
+func (b *retryBudget) available() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(0)
+	return b.tokens >= 1
+}
+
+// withdraw расходует бюджет на повтор. Возвращает false, если бюджет исчерпан.
+func (b *retryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(0)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// refill добавляет токены за прошедшее время и extra. Вызывается под mu.
+func (b *retryBudget) refill(extra float64) {
+	now := time.Now()
+	b.tokens = min(b.tokens+now.Sub(b.updated).Seconds()*b.perSecond+extra, b.capacity)
+	b.updated = now
+}
+
+// rewind подставляет в запрос буферизованное тело для очередной попытки.
+func rewind(r *http.Request, body []byte) {
+	if body != nil {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+}
@@ -0,0 +1,165 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/config"
+	"github.com/DblMOKRQ/cloud_test_task/internal/router/forwarded"
+	"github.com/DblMOKRQ/cloud_test_task/internal/router/proxy"
+	"github.com/DblMOKRQ/cloud_test_task/internal/router/rewrite"
+	logger "github.com/DblMOKRQ/cloud_test_task/pkg"
+	"go.uber.org/zap"
+)
+
+func TestRetryBudgetRatio(t *testing.T) {
+	b := newRetryBudget(config.RetryBudget{Ratio: 0.2})
+	retries := 0
+	for i := 0; i < 1000; i++ {
+		b.deposit()
+		if b.withdraw() {
+			retries++
+		}
+	}
+	// Начальный запас — один токен
+	if retries > 201 {
+		t.Fatalf("retries = %d for 1000 requests, want at most 201", retries)
+	}
+	if retries < 199 {
+		t.Fatalf("retries = %d for 1000 requests, want about 200", retries)
+	}
+}
+
+func TestRetryBudgetMinPerSecond(t *testing.T) {
+	b := newRetryBudget(config.RetryBudget{MinPerSecond: 10})
+	for b.withdraw() {
+	}
+	if b.available() {
+		t.Fatal("budget available right after it was exhausted")
+	}
+	b.updated = b.updated.Add(-time.Second)
+	retries := 0
+	for b.withdraw() {
+		retries++
+	}
+	if retries < 10 || retries > 11 {
+		t.Fatalf("retries after 1s = %d, want 10", retries)
+	}
+}
+
+// Когда бюджет исчерпан, неудачный запрос не повторяется,
+// а клиент получает ответ первого backend-а.
+func TestHandleRequestSkipsRetryWhenBudgetExhausted(t *testing.T) {
+	var hits atomic.Int32
+	unavailable := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	a, b := httptest.NewServer(unavailable), httptest.NewServer(unavailable)
+	defer a.Close()
+	defer b.Close()
+
+	rt := newTestRouter(t, []string{a.URL, b.URL}, config.Retry{
+		Attempts: 3,
+		OnStatus: []int{http.StatusServiceUnavailable},
+		Methods:  []string{http.MethodGet},
+		Budget:   config.RetryBudget{Ratio: 0.1},
+	})
+
+	// Первый запрос расходует начальный запас: 2 попытки
+	// (второй backend тоже отвечает 503, а других нет)
+	serveTest(rt)
+	if got := hits.Swap(0); got != 2 {
+		t.Fatalf("first request: %d backend hits, want 2", got)
+	}
+
+	// Бюджет пуст: без повтора
+	w := serveTest(rt)
+	if got := hits.Load(); got != 1 {
+		t.Fatalf("second request: %d backend hits, want 1", got)
+	}
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("second request: status %d, want 503 from the backend", w.Code)
+	}
+}
+
+// Все попытки одного запроса без X-Request-Id получают один {request_id}.
+func TestRetryKeepsRequestID(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		ids []string
+	)
+	record := func(status int) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			ids = append(ids, r.Header.Get("X-Trace-Id"))
+			mu.Unlock()
+			w.WriteHeader(status)
+		})
+	}
+	a, b := httptest.NewServer(record(http.StatusServiceUnavailable)), httptest.NewServer(record(http.StatusServiceUnavailable))
+	defer a.Close()
+	defer b.Close()
+
+	rt := newTestRouter(t, []string{a.URL, b.URL}, config.Retry{
+		Attempts: 2,
+		OnStatus: []int{http.StatusServiceUnavailable},
+		Methods:  []string{http.MethodGet},
+		Budget:   config.RetryBudget{Ratio: 1, MinPerSecond: 10},
+	})
+	rules, err := rewrite.New(config.Rewrite{RequestHeaders: config.HeaderRules{
+		Set: map[string]string{"X-Trace-Id": "{request_id}"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt.state.Load().fallback.rewrite = rules
+
+	serveTest(rt)
+	if len(ids) != 2 {
+		t.Fatalf("%d attempts, want 2", len(ids))
+	}
+	if ids[0] == "" || ids[0] != ids[1] {
+		t.Fatalf("request ids %q, want the same id for every attempt", ids)
+	}
+}
+
+func newTestRouter(t *testing.T, backends []string, retry config.Retry) *Router {
+	t.Helper()
+	log := &logger.Logger{Logger: zap.NewNop()}
+	fwd, err := forwarded.NewResolver(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Pool{
+		Balancer:      config.Balancer{Algorithm: "roundrobin"},
+		HealthChecker: config.HealthChecker{Interval: time.Minute, Timeout: time.Second, Concurrency: 1},
+	}
+	for _, u := range backends {
+		cfg.Backends = append(cfg.Backends, config.Backend{URL: u})
+	}
+	transport := proxy.NewTransport(config.Transport{})
+	t.Cleanup(transport.CloseIdleConnections)
+	p, err := newPool(config.DefaultPool, cfg, config.CircuitBreaker{}, transport, fwd, proxy.NewStreams(config.Streaming{}), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt := &Router{log: log}
+	rt.state.Store(&state{
+		pools:    map[string]*pool{p.name: p},
+		fallback: &route{pool: p},
+		retry:    newRetryPolicy(retry),
+	})
+	return rt
+}
+
+func serveTest(rt *Router) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	rt.HandleRequest(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return w
+}
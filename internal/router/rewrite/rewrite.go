@@ -0,0 +1,146 @@
+package rewrite
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/config"
+)
+
+// RequestIDHeader — заголовок, из которого берется идентификатор запроса.
+const RequestIDHeader = "X-Request-Id"
+
+// Vars содержит значения, подставляемые в шаблоны заголовков.
+type Vars struct {
+	ClientIP  string // {client_ip}
+	RequestID string // {request_id}
+	Backend   string // {backend} — хост backend-сервера
+	Pool      string // {pool}
+	Host      string // {host} — исходный хост запроса
+}
+
+func (v Vars) replacer() *strings.Replacer {
+	return strings.NewReplacer(
+		"{client_ip}", v.ClientIP,
+		"{request_id}", v.RequestID,
+		"{backend}", v.Backend,
+		"{pool}", v.Pool,
+		"{host}", v.Host,
+	)
+}
+
+// headerRules описывает изменения заголовков.
+type headerRules struct {
+	add    map[string]string
+	set    map[string]string
+	remove []string
+}
+
+func newHeaderRules(cfg config.HeaderRules) headerRules {
+	return headerRules{add: cfg.Add, set: cfg.Set, remove: cfg.Remove}
+}
+
+func (hr headerRules) empty() bool {
+	return len(hr.add) == 0 && len(hr.set) == 0 && len(hr.remove) == 0
+}
+
+// apply удаляет, устанавливает и добавляет заголовки в указанном порядке.
+func (hr headerRules) apply(h http.Header, rep *strings.Replacer) {
+	for _, name := range hr.remove {
+		h.Del(name)
+	}
+	for name, value := range hr.set {
+		h.Set(name, rep.Replace(value))
+	}
+	for name, value := range hr.add {
+		h.Add(name, rep.Replace(value))
+	}
+}
+
+// Rules — правила переписывания запросов и ответов маршрута.
+type Rules struct {
+	stripPrefix   string
+	replacePrefix string
+	pathRegex     *regexp.Regexp
+	pathReplace   string
+	request       headerRules
+	response      headerRules
+}
+
+// New компилирует правила переписывания из конфига.
+// Возвращает nil, если правила не заданы, и ошибку при некорректном регулярном выражении.
+func New(cfg config.Rewrite) (*Rules, error) {
+	rules := &Rules{
+		stripPrefix:   cfg.StripPrefix,
+		replacePrefix: cfg.ReplacePrefix,
+		pathReplace:   cfg.PathReplacement,
+		request:       newHeaderRules(cfg.RequestHeaders),
+		response:      newHeaderRules(cfg.ResponseHeaders),
+	}
+	if cfg.PathRegex != "" {
+		re, err := regexp.Compile(cfg.PathRegex)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile path_regex: %v", err)
+		}
+		rules.pathRegex = re
+	}
+	if rules.stripPrefix == "" && rules.pathRegex == nil && rules.request.empty() && rules.response.empty() {
+		return nil, nil
+	}
+	return rules, nil
+}
+
+// Request переписывает путь и заголовки исходящего запроса.
+// Запрос должен быть копией, принадлежащей вызывающему.
+func (rules *Rules) Request(r *http.Request, vars Vars) {
+	path := r.URL.Path
+	if rules.stripPrefix != "" {
+		if rest, ok := strings.CutPrefix(path, rules.stripPrefix); ok {
+			path = rules.replacePrefix + rest
+		}
+	}
+	if rules.pathRegex != nil {
+		path = rules.pathRegex.ReplaceAllString(path, rules.pathReplace)
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	if path != r.URL.Path {
+		r.URL.Path = path
+		r.URL.RawPath = ""
+	}
+
+	rules.request.apply(r.Header, vars.replacer())
+}
+
+// Response изменяет заголовки ответа backend-а.
+func (rules *Rules) Response(h http.Header, vars Vars) {
+	rules.response.apply(h, vars.replacer())
+}
+
+type requestIDKey struct{}
+
+// WithRequestID закрепляет за запросом идентификатор, чтобы все попытки
+// его проксирования получили один и тот же {request_id}.
+func WithRequestID(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), requestIDKey{}, RequestID(r)))
+}
+
+// RequestID возвращает идентификатор, закрепленный через WithRequestID,
+// из заголовка X-Request-Id или генерирует новый, если их нет.
+func RequestID(r *http.Request) string {
+	if id, ok := r.Context().Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	if id := r.Header.Get(RequestIDHeader); id != "" {
+		return id
+	}
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,88 @@
+package router
+
+import (
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/DblMOKRQ/cloud_test_task/internal/config"
+	"github.com/DblMOKRQ/cloud_test_task/internal/router/rewrite"
+)
+
+// route направляет запросы, удовлетворяющие всем условиям, в пул.
+type route struct {
+	host       string // Хост в нижнем регистре; "*.example.com" совпадает с любым поддоменом
+	pathPrefix string
+	pathRegex  *regexp.Regexp
+	methods    map[string]struct{}
+	headers    map[string]string
+	rewrite    *rewrite.Rules // Правила переписывания, nil — не заданы
+	pool       *pool
+}
+
+// newRoute создает маршрут по настройкам из конфига.
+// Возвращает ошибку при некорректном регулярном выражении.
+func newRoute(cfg config.Route, p *pool) (*route, error) {
+	rules, err := rewrite.New(cfg.Rewrite)
+	if err != nil {
+		return nil, err
+	}
+	rt := &route{
+		host:       strings.ToLower(cfg.Host),
+		pathPrefix: cfg.PathPrefix,
+		headers:    cfg.Headers,
+		rewrite:    rules,
+		pool:       p,
+	}
+	if cfg.PathRegex != "" {
+		re, err := regexp.Compile(cfg.PathRegex)
+		if err != nil {
+			return nil, err
+		}
+		rt.pathRegex = re
+	}
+	if len(cfg.Methods) > 0 {
+		rt.methods = make(map[string]struct{}, len(cfg.Methods))
+		for _, m := range cfg.Methods {
+			rt.methods[strings.ToUpper(m)] = struct{}{}
+		}
+	}
+	return rt, nil
+}
+
+// match сообщает, подходит ли запрос под маршрут.
+func (rt *route) match(r *http.Request) bool {
+	if rt.host != "" && !matchHost(rt.host, r.Host) {
+		return false
+	}
+	if rt.pathPrefix != "" && !strings.HasPrefix(r.URL.Path, rt.pathPrefix) {
+		return false
+	}
+	if rt.pathRegex != nil && !rt.pathRegex.MatchString(r.URL.Path) {
+		return false
+	}
+	if rt.methods != nil {
+		if _, ok := rt.methods[r.Method]; !ok {
+			return false
+		}
+	}
+	for name, value := range rt.headers {
+		if r.Header.Get(name) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// matchHost сравнивает хост запроса (без порта) с шаблоном маршрута.
+func matchHost(pattern, host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		return strings.HasSuffix(host, suffix)
+	}
+	return host == pattern
+}
@@ -2,27 +2,42 @@ package router
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/DblMOKRQ/cloud_test_task/internal/config"
 	"github.com/DblMOKRQ/cloud_test_task/internal/models"
 	"github.com/DblMOKRQ/cloud_test_task/internal/ratelimiter"
-	"github.com/DblMOKRQ/cloud_test_task/internal/router/backend/healthcheck"
+	"github.com/DblMOKRQ/cloud_test_task/internal/router/certs"
 	"github.com/DblMOKRQ/cloud_test_task/internal/router/errs"
+	"github.com/DblMOKRQ/cloud_test_task/internal/router/forwarded"
 	"github.com/DblMOKRQ/cloud_test_task/internal/router/proxy"
+	"github.com/DblMOKRQ/cloud_test_task/internal/router/rewrite"
 	logger "github.com/DblMOKRQ/cloud_test_task/pkg"
 	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 type balancer interface {
-	Next() *models.Server
+	Next(r *http.Request) *models.Server
+	// SetServers заменяет список серверов балансировщика.
+	SetServers(servers []*models.Server)
+}
+
+// affinityBinder реализуется балансировщиками, которые закрепляют клиента за сервером.
+type affinityBinder interface {
+	Bind(w http.ResponseWriter, r *http.Request, server *models.Server)
 }
 
 // Router обрабатывает HTTP-запросы и управляет балансировкой.
@@ -30,40 +45,101 @@ type Router struct {
 	Host       string
 	Port       string
 	RL         *ratelimiter.RedisRateLimiter
-	bal        balancer
+	state      atomic.Pointer[state] // Пулы и маршруты, заменяемые при перезагрузке конфигурации
 	log        *logger.Logger
 	server     *http.Server
-	hc         *healthcheck.HealthChecker
+	redirect   *http.Server // HTTP-слушатель, перенаправляющий на HTTPS
+	admin      *http.Server // Слушатель admin API, nil — admin API отключен
+	certs      *certs.Store
+	streams    *proxy.Streams // WebSocket и потоковые ответы
+	transport  *http.Transport
+	fwd        *forwarded.Resolver
 	shutdownWg sync.WaitGroup
-	cfg        *config.Config
+	cfg        *config.Config // Конфигурация, с которой запущен роутер
+}
+
+// state — пулы, маршруты и политика повторов, которые атомарно
+// заменяются целиком при перезагрузке конфигурации.
+type state struct {
+	cfg      *config.Config
+	pools    map[string]*pool
+	routes   []*route
+	fallback *route // Маршрут в пул default для запросов, не подошедших ни под один маршрут
+	retry    *retryPolicy
 }
 
 // NewRouter создает новый экземпляр роутера с настройками из конфига
 // Возвращает ошибку если не удалось инициализировать компоненты
-func NewRouter(cfg *config.Config, bal balancer, log *logger.Logger, hc *healthcheck.HealthChecker) (*Router, error) {
-	mux := http.NewServeMux()
-
+func NewRouter(cfg *config.Config, log *logger.Logger) (*Router, error) {
+	fwd, err := forwarded.NewResolver(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
 	rt := &Router{
-		Host: cfg.Host,
-		Port: cfg.Port,
-		RL: ratelimiter.InitRedisClient(
-			fmt.Sprintf("%s:%d", cfg.Storage.Redis.Host, cfg.Storage.Redis.Port),
-			cfg.Storage.Redis.Password,
-			log,
-			cfg.Rate_limiting.Rate_per_second,
-			cfg.Rate_limiting.Capacity,
-		),
-		bal: bal,
-		log: log,
-		hc:  hc,
-		cfg: cfg,
+		Host:      cfg.Host,
+		Port:      cfg.Port,
+		log:       log,
+		streams:   proxy.NewStreams(cfg.Streaming),
+		transport: proxy.NewTransport(cfg.Transport),
+		fwd:       fwd,
+		cfg:       cfg,
 	}
+	st, err := rt.newState(cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+	rt.state.Store(st)
+
+	var (
+		store  *certs.Store
+		tlsCfg *tls.Config
+	)
+	if cfg.TLS.Enabled {
+		store, err = certs.NewStore(cfg.TLS.Certificates, log)
+		if err != nil {
+			return nil, err
+		}
+		if tlsCfg, err = store.TLSConfig(cfg.TLS); err != nil {
+			return nil, err
+		}
+	}
+
+	mux := http.NewServeMux()
+
+	rt.certs = store
+	rt.RL = ratelimiter.InitRedisClient(
+		fmt.Sprintf("%s:%d", cfg.Storage.Redis.Host, cfg.Storage.Redis.Port),
+		cfg.Storage.Redis.Password,
+		log,
+		cfg.Rate_limiting.Rate_per_second,
+		cfg.Rate_limiting.Capacity,
+	)
 	mux.HandleFunc("/", rt.HandleRequest)
-	mux.HandleFunc("/edit", rt.HandleEdit)
-	handler := rt.RL.RateLimitMiddleware(mux)
+	handler := fwd.Middleware(rt.RL.RateLimitMiddleware(mux))
 	rt.server = &http.Server{
-		Addr:    fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
-		Handler: handler,
+		Addr:      fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
+		Handler:   handler,
+		TLSConfig: tlsCfg,
+	}
+	h2s := &http2.Server{MaxConcurrentStreams: cfg.HTTP2.MaxConcurrentStreams}
+	if err := http2.ConfigureServer(rt.server, h2s); err != nil {
+		rt.RL.Close()
+		return nil, fmt.Errorf("configure http2: %v", err)
+	}
+	if cfg.HTTP2.H2C && !cfg.TLS.Enabled {
+		rt.server.Handler = h2c.NewHandler(handler, h2s)
+	}
+	if cfg.TLS.Enabled && cfg.TLS.RedirectPort != "" {
+		rt.redirect = &http.Server{
+			Addr:    fmt.Sprintf("%s:%s", cfg.Host, cfg.TLS.RedirectPort),
+			Handler: http.HandlerFunc(rt.redirectHTTPS),
+		}
+	}
+	if cfg.Admin.Enabled {
+		if rt.admin, err = rt.newAdminServer(cfg.Admin); err != nil {
+			rt.RL.Close()
+			return nil, err
+		}
 	}
 
 	return rt, nil
@@ -71,21 +147,213 @@ func NewRouter(cfg *config.Config, bal balancer, log *logger.Logger, hc *healthc
 }
 
 // HandleRequest обрабатывает входящие HTTP-запросы.
-// Перенаправляет запросы через балансировщик на backend-серверы.
+// Выбирает пул по маршрутам и перенаправляет запрос через его балансировщик
+// на backend-сервер. Неудачные попытки повторяемых запросов повторяются на других серверах.
 func (rt *Router) HandleRequest(w http.ResponseWriter, r *http.Request) {
-	backend := rt.bal.Next()
-	if backend == nil {
-		rt.log.Error("No backend available")
-		errs.JSONError(w, errs.ErrorResponse{Error: "Service is unavailable"}, http.StatusBadGateway)
+	st := rt.state.Load()
+	route := st.match(r)
+	if route == nil {
+		errs.JSONError(w, errs.ErrorResponse{Error: "No route matched"}, http.StatusNotFound)
+		return
+	}
+	p := route.pool
+
+	attempts, body, err := st.retry.prepare(r)
+	if err != nil {
+		rt.log.Error("Failed to read request body", zap.Error(err))
+		errs.JSONError(w, errs.ErrorResponse{Error: "Invalid request body"}, http.StatusBadRequest)
+		return
+	}
+
+	if route.rewrite != nil {
+		// Повторы получают тот же {request_id}, что и первая попытка
+		r = rewrite.WithRequestID(r)
+	}
+
+	budget := st.retry.budget
+	budget.deposit()
+
+	binder, _ := p.bal.(affinityBinder)
+	var discarded *proxy.Response // Последний ответ backend-а, отброшенный ради повтора
+	for attempt := 0; attempt < attempts; attempt++ {
+		backend := p.bal.Next(r)
+		if backend == nil {
+			rt.log.Error("No backend available", zap.String("pool", p.name), zap.Int("attempt", attempt+1))
+			if discarded != nil {
+				discarded.Write(w)
+				return
+			}
+			errs.JSONError(w, errs.ErrorResponse{Error: "Service is unavailable"}, http.StatusBadGateway)
+			return
+		}
+		if backend.Breaker != nil && !backend.Breaker.Allow() {
+			// Breaker закрылся после выбора сервера: выбираем другой, не тратя попытку
+			r = r.WithContext(models.WithExcluded(r.Context(), backend))
+			attempt--
+			continue
+		}
+		if binder != nil {
+			// Cookie предыдущей неудачной попытки заменяется привязкой к новому серверу
+			w.Header().Del("Set-Cookie")
+			binder.Bind(w, r, backend)
+		}
+
+		rewind(r, body)
+		canRetry := attempt < attempts-1 && p.hasAlternative(r, backend) && budget.available()
+		a := rt.serve(w, r, st.retry, route, backend, canRetry)
+		if !a.Failed {
+			rt.log.Debug("Request proxied", zap.String("backend", backend.URL.String()))
+			return
+		}
+		if a.Discarded != nil {
+			discarded = a.Discarded
+		}
+		if r.Context().Err() != nil {
+			return
+		}
+		if !budget.withdraw() {
+			// Бюджет израсходован другими запросами после начала попытки
+			rt.log.Warn("Retry budget exhausted", zap.String("pool", p.name), zap.String("failed_backend", backend.URL.String()))
+			if discarded != nil {
+				discarded.Write(w)
+				return
+			}
+			errs.JSONError(w, errs.ErrorResponse{Error: "Service is unavailable"}, http.StatusBadGateway)
+			return
+		}
+
+		rt.log.Warn("Retrying request on another backend",
+			zap.String("pool", p.name),
+			zap.String("client_ip", forwarded.ClientIP(r)),
+			zap.String("failed_backend", backend.URL.String()),
+			zap.Int("attempt", attempt+1),
+		)
+		r = r.WithContext(models.WithExcluded(r.Context(), backend))
+	}
+}
+
+// match возвращает первый подходящий маршрут или маршрут в пул по умолчанию.
+// Возвращает nil, если запрос не подошел ни под один маршрут и пула по умолчанию нет.
+func (st *state) match(r *http.Request) *route {
+	for _, route := range st.routes {
+		if route.match(r) {
+			return route
+		}
+	}
+	return st.fallback
+}
+
+// fallbackRoute возвращает маршрут без условий в пул по умолчанию или nil, если пула нет.
+func fallbackRoute(pools map[string]*pool) *route {
+	p, ok := pools[config.DefaultPool]
+	if !ok {
+		return nil
+	}
+	return &route{pool: p}
+}
+
+// serve проксирует одну попытку запроса на сервер пула маршрута.
+// Возвращает попытку; Failed — попытка не удалась и ответ клиенту не записан.
+func (rt *Router) serve(w http.ResponseWriter, r *http.Request, retry *retryPolicy, route *route, backend *models.Server, canRetry bool) *proxy.Attempt {
+	p := route.pool
+	backend.Acquire()
+	defer backend.Release()
+
+	clientCtx := r.Context()
+	attempt := &proxy.Attempt{
+		CanRetry:    canRetry,
+		RetryStatus: retry.statuses,
+		Client:      clientCtx,
+	}
+	if retry.perTry > 0 {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		timer := time.AfterFunc(retry.perTry, cancel)
+		defer timer.Stop()
+		attempt.OnResponse = func() { timer.Stop() }
+		r = r.WithContext(ctx)
+	}
+	if route.rewrite != nil {
+		vars := rewrite.Vars{
+			ClientIP:  forwarded.ClientIP(r),
+			RequestID: rewrite.RequestID(r),
+			Backend:   backend.URL.Host,
+			Pool:      p.name,
+			Host:      r.Host,
+		}
+		r = r.Clone(r.Context())
+		route.rewrite.Request(r, vars)
+		attempt.Rewrite = func(h http.Header) { route.rewrite.Response(h, vars) }
+	}
+
+	// Учет выполняется в defer: при обрыве потока reverse proxy
+	// завершает обработчик паникой http.ErrAbortHandler.
+	start := time.Now()
+	defer func() {
+		latency := time.Since(start)
+		if attempt.Stream {
+			// Для потока учитывается время до заголовков, а не длительность соединения
+			latency = attempt.Responded.Sub(start)
+		}
+		// Отмена клиентом ничего не говорит о сервере
+		canceled := clientCtx.Err() != nil && !attempt.Stream
+		failed := attempt.Err != nil || attempt.Status >= http.StatusInternalServerError
+		switch {
+		case canceled:
+		case failed:
+			backend.ObserveFailure(max(latency, retry.perTry))
+		default:
+			backend.ObserveLatency(latency)
+		}
+
+		if backend.Breaker != nil {
+			if canceled {
+				backend.Breaker.Cancel()
+			} else {
+				backend.Breaker.Done(failed, latency)
+			}
+		}
+	}()
+
+	p.proxies.Get(backend).ServeHTTP(w, proxy.WithAttempt(r, attempt))
+	return attempt
+}
+
+// HandleBreakers возвращает состояние circuit breaker-ов backend-серверов.
+func (rt *Router) HandleBreakers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errs.JSONError(w, errs.ErrorResponse{Error: "Only GET method is allowed"}, http.StatusMethodNotAllowed)
 		return
 	}
 
-	proxy.Proxy(backend.URL, rt.log).ServeHTTP(w, r)
-	rt.log.Info("Request proxied to ", zap.Any("URL", backend.URL))
+	type breakerState struct {
+		Pool    string `json:"pool"`
+		Backend string `json:"backend"`
+		Alive   bool   `json:"alive"`
+		State   string `json:"state"`
+	}
+	states := make([]breakerState, 0)
+	for _, p := range rt.state.Load().sortedPools() {
+		for _, server := range p.list() {
+			state := "disabled"
+			if server.Breaker != nil {
+				state = server.Breaker.State()
+			}
+			states = append(states, breakerState{
+				Pool:    p.name,
+				Backend: server.URL.String(),
+				Alive:   server.IsAlive(),
+				State:   state,
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(states)
 }
 
 // HandleEdit обрабатывает запросы на изменение лимитов.
-// Принимает JSON с новыми значениями rate limit.
+// Принимает JSON с новыми значениями rate limit, возвращает установленный лимит в JSON.
 func (rt *Router) HandleEdit(w http.ResponseWriter, r *http.Request) {
 
 	if r.Method != http.MethodPost {
@@ -106,9 +374,9 @@ func (rt *Router) HandleEdit(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	// Валидация данных
-	if request.UserIP == "" {
-		errs.JSONError(w, errs.ErrorResponse{Error: "userIP is required"}, http.StatusBadRequest)
+	// Валидация данных: IP приводится к виду, под которым его видит ограничитель
+	userIP, ok := parseClient(w, request.UserIP)
+	if !ok {
 		return
 	}
 
@@ -118,17 +386,21 @@ func (rt *Router) HandleEdit(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Обновляем лимит
-	if err := rt.RL.SetUserLimit(request.UserIP, request.NewRate, request.NewBurst); err != nil {
+	var before any
+	if o, ok := rt.RL.UserLimit(userIP); ok {
+		before = newLimitInfo(userIP, o)
+	}
+	if err := rt.RL.SetUserLimit(r.Context(), userIP, request.NewRate, request.NewBurst, 0); err != nil {
 		rt.log.Error("Failed to update rate limit",
-			zap.String("userIP", request.UserIP),
+			zap.String("userIP", userIP),
 			zap.Error(err),
 		)
 		errs.JSONError(w, errs.ErrorResponse{Error: "Failed to update rate limit"}, http.StatusInternalServerError)
 		return
 	}
-
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(fmt.Sprintf("Rate limit updated for %s: %d/s (burst %d)", request.UserIP, request.NewRate, request.NewBurst)))
+	info := newLimitInfo(userIP, ratelimiter.Override{Rate: request.NewRate, Burst: request.NewBurst})
+	rt.audit(r, "rate_limit.set", userIP, before, info)
+	writeJSON(w, info, http.StatusOK)
 }
 
 // Run запускает HTTP-сервер и healthchecker.
@@ -137,35 +409,103 @@ func (rt *Router) Run() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	// Запуск health checker
+	// Запуск health checker-ов пулов
+	for _, p := range rt.state.Load().pools {
+		p.start(ctx, &rt.shutdownWg)
+	}
+
+	// Синхронизация индивидуальных лимитов с другими экземплярами
 	rt.shutdownWg.Add(1)
 	go func() {
 		defer rt.shutdownWg.Done()
-		rt.hc.Run(ctx)
+		rt.RL.Run(ctx)
 	}()
 
+	// Перечитывание сертификатов с диска
+	if rt.certs != nil {
+		rt.shutdownWg.Add(1)
+		go func() {
+			defer rt.shutdownWg.Done()
+			rt.certs.Run(ctx, rt.cfg.TLS.ReloadInterval)
+		}()
+	}
+
 	// Запуск HTTP сервера
 	rt.shutdownWg.Add(1)
 	go func() {
 		defer rt.shutdownWg.Done()
-		rt.log.Info("Starting server", zap.String("address", rt.server.Addr))
-		if err := rt.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		rt.log.Info("Starting server", zap.String("address", rt.server.Addr), zap.Bool("tls", rt.certs != nil))
+		var err error
+		if rt.certs != nil {
+			err = rt.server.ListenAndServeTLS("", "")
+		} else {
+			err = rt.server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			rt.log.Error("Server error", zap.Error(err))
 			stop() // Инициируем shutdown при ошибке
 		}
 	}()
 
-	// Ожидание сигнала завершения
-	<-ctx.Done()
+	// Запуск слушателя с перенаправлением на HTTPS
+	if rt.redirect != nil {
+		rt.shutdownWg.Add(1)
+		go func() {
+			defer rt.shutdownWg.Done()
+			rt.log.Info("Starting HTTPS redirect server", zap.String("address", rt.redirect.Addr))
+			if err := rt.redirect.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				rt.log.Error("Redirect server error", zap.Error(err))
+				stop()
+			}
+		}()
+	}
+
+	// Запуск слушателя admin API
+	if rt.admin != nil {
+		rt.shutdownWg.Add(1)
+		go func() {
+			defer rt.shutdownWg.Done()
+			if err := rt.serveAdmin(); err != nil && err != http.ErrServerClosed {
+				rt.log.Error("Admin server error", zap.Error(err))
+				stop()
+			}
+		}()
+	} else {
+		rt.log.Info("Admin API is disabled")
+	}
+
+	// Перезагрузка конфигурации до сигнала завершения
+	rt.watchReload(ctx)
 	rt.log.Info("Shutting down server...")
 
 	// Graceful shutdown HTTP сервера
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// Потоки не завершаются сами при остановке сервера: WebSocket-соединения
+	// перехвачены у http.Server, а SSE задерживают Shutdown до таймаута.
+	rt.shutdownWg.Add(1)
+	go func() {
+		defer rt.shutdownWg.Done()
+		if n := rt.streams.Active(); n > 0 {
+			rt.log.Info("Draining streams", zap.Int("active", n), zap.Duration("timeout", rt.cfg.Streaming.DrainTimeout))
+		}
+		rt.streams.Shutdown(rt.cfg.Streaming.DrainTimeout)
+	}()
+
 	if err := rt.server.Shutdown(shutdownCtx); err != nil {
 		rt.log.Error("Server shutdown error", zap.Error(err))
 	}
+	if rt.redirect != nil {
+		if err := rt.redirect.Shutdown(shutdownCtx); err != nil {
+			rt.log.Error("Redirect server shutdown error", zap.Error(err))
+		}
+	}
+	if rt.admin != nil {
+		if err := rt.admin.Shutdown(shutdownCtx); err != nil {
+			rt.log.Error("Admin server shutdown error", zap.Error(err))
+		}
+	}
 
 	// Закрытие Redis соединения
 	rt.RL.Close()
@@ -189,3 +529,30 @@ func (rt *Router) GracefulShutdown(ctx context.Context) error {
 
 	return rt.server.Shutdown(ctx)
 }
+
+// redirectHTTPS перенаправляет запрос на тот же адрес по HTTPS.
+func (rt *Router) redirectHTTPS(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(r.Host); err == nil {
+		host = h
+	}
+	if rt.Port != "443" {
+		host = net.JoinHostPort(host, rt.Port)
+	}
+
+	code := http.StatusPermanentRedirect
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		code = http.StatusMovedPermanently
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), code)
+}
+
+// sortedPools возвращает пулы, упорядоченные по имени.
+func (st *state) sortedPools() []*pool {
+	pools := make([]*pool, 0, len(st.pools))
+	for _, p := range st.pools {
+		pools = append(pools, p)
+	}
+	sort.Slice(pools, func(i, j int) bool { return pools[i].name < pools[j].name })
+	return pools
+}
@@ -38,3 +38,8 @@ func NewLogger(production bool) (*Logger, error) {
 func (l *Logger) Nop() *zap.Logger {
 	return zap.NewNop()
 }
+
+// With возвращает логгер, добавляющий указанные поля ко всем записям
+func (l *Logger) With(fields ...zap.Field) *Logger {
+	return &Logger{Logger: l.Logger.With(fields...)}
+}